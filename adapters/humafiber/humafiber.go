@@ -3,6 +3,7 @@ package humafiber
 import (
 	"context"
 	"io"
+	"log/slog"
 	"mime/multipart"
 	"net/http"
 	"net/url"
@@ -14,8 +15,9 @@ import (
 )
 
 type fiberCtx struct {
-	op   *huma.Operation
-	orig *fiber.Ctx
+	op     *huma.Operation
+	orig   *fiber.Ctx
+	logger *slog.Logger
 }
 
 func (c *fiberCtx) Operation() *huma.Operation {
@@ -38,8 +40,17 @@ func (c *fiberCtx) Host() string {
 	return c.orig.Hostname()
 }
 
+func (c *fiberCtx) RemoteAddr() string {
+	return c.orig.Context().RemoteAddr().String()
+}
+
 func (c *fiberCtx) URL() url.URL {
-	u, _ := url.Parse(string(c.orig.Request().RequestURI()))
+	uri := string(c.orig.Request().RequestURI())
+	u, err := url.Parse(uri)
+	if err != nil {
+		c.logger.Warn("unable to parse request URI", "uri", uri, "error", err)
+		return url.URL{}
+	}
 	return *u
 }
 
@@ -96,6 +107,7 @@ func (c *fiberCtx) BodyWriter() io.Writer {
 
 type fiberAdapter struct {
 	router *fiber.App
+	logger *slog.Logger
 }
 
 func (a *fiberAdapter) Handle(op *huma.Operation, handler func(huma.Context)) {
@@ -104,18 +116,18 @@ func (a *fiberAdapter) Handle(op *huma.Operation, handler func(huma.Context)) {
 	path = strings.ReplaceAll(path, "{", ":")
 	path = strings.ReplaceAll(path, "}", "")
 	a.router.Add(op.Method, path, func(c *fiber.Ctx) error {
-		ctx := &fiberCtx{op: op, orig: c}
+		ctx := &fiberCtx{op: op, orig: c, logger: a.logger}
 		handler(ctx)
 		return nil
 	})
 }
 
 func (a *fiberAdapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// b, _ := httputil.DumpRequest(r, true)
-	// fmt.Println(string(b))
 	resp, err := a.router.Test(r)
 	if err != nil {
-		panic(err)
+		a.logger.Error("unable to handle request", "method", r.Method, "path", r.URL.Path, "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
 	}
 	h := w.Header()
 	for k, v := range resp.Header {
@@ -128,5 +140,9 @@ func (a *fiberAdapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 func New(r *fiber.App, config huma.Config) huma.API {
-	return huma.NewAPI(config, &fiberAdapter{router: r})
+	a := &fiberAdapter{router: r}
+	api := huma.NewAPI(config, a)
+	// Adapter errors which cannot be returned are logged like the core's.
+	a.logger = api.Logger()
+	return api
 }
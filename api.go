@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"mime/multipart"
 	"net/http"
 	"net/url"
@@ -68,6 +69,37 @@ type Context interface {
 	BodyWriter() io.Writer
 }
 
+// Middlewares is a list of standard middleware functions that can be applied
+// to operations. Each middleware is passed the current context and a `next`
+// function which must be called to continue processing the request. A
+// middleware may wrap the context to modify e.g. its body reader or writer.
+//
+//	func MyMiddleware(ctx huma.Context, next func(huma.Context)) {
+//		// Do something before the handler...
+//		next(ctx)
+//		// Do something after the handler...
+//	}
+type Middlewares []func(ctx Context, next func(Context))
+
+// Handler builds and returns a handler func from the chain of middlewares,
+// with `endpoint func` as the final handler.
+func (m Middlewares) Handler(endpoint func(Context)) func(Context) {
+	if len(m) == 0 {
+		return endpoint
+	}
+
+	// Wrap the end handler with the middleware chain, starting with the last
+	// middleware so the first one runs first.
+	h := endpoint
+	for i := len(m) - 1; i >= 0; i-- {
+		mw, next := m[i], h
+		h = func(ctx Context) {
+			mw(ctx, next)
+		}
+	}
+	return h
+}
+
 // Transformer is a function that can modify a response body before it is
 // serialized. The `status` is the HTTP status code for the response and `v` is
 // the value to be serialized. The return value is the new value to be
@@ -88,6 +120,12 @@ type Config struct {
 	DocsPath    string
 	SchemasPath string
 
+	// ProblemsPath is the path to serve human-readable documentation pages for
+	// problem types registered in `huma.Problems`, e.g. `/problems` will serve
+	// `/problems/out-of-credit`. It should match the registry's URI prefix.
+	// Disabled by default.
+	ProblemsPath string
+
 	// Formats defines the supported request/response formats by content type or
 	// extension (e.g. `json` for `application/my-format+json`).
 	Formats map[string]Format
@@ -99,9 +137,34 @@ type Config struct {
 
 	// Transformers are a way to modify a response body before it is serialized.
 	Transformers []Transformer
+
+	// Timeout is the default maximum amount of time an operation's handler
+	// may run. See `Operation.Timeout`. Defaults to no timeout.
+	Timeout time.Duration
+
+	// IdempotencyStore saves responses for operations using
+	// `Operation.IdempotencyKey`. Defaults to an in-memory store which keeps
+	// keys for 24 hours.
+	IdempotencyStore IdempotencyStore
+
+	// IdempotencyScope returns the client, e.g. an authenticated user or API
+	// key, which idempotency keys belong to. Keys are only matched within the
+	// same scope so different clients reusing a key never receive each
+	// other's responses. Defaults to sharing keys across all clients.
+	IdempotencyScope func(ctx Context) string
+
+	// Logger is used to log warnings and errors which cannot be returned to
+	// the client, such as response marshaling failures after the status has
+	// been written or clients disconnecting mid-request. Defaults to
+	// `slog.Default()`.
+	Logger *slog.Logger
 }
 
-// API represents a Huma API wrapping a specific router.
+// API represents a Huma API wrapping a specific router. Create one via
+// `huma.NewAPI` or an adapter package rather than implementing it yourself,
+// since methods may be added as the framework grows, e.g. `UseMiddleware`
+// which gives every router the same middleware chain. Wrappers should embed
+// the `API` they wrap to remain compatible.
 type API interface {
 	// Adapter returns the router adapter for this API, providing a generic
 	// interface to get request information and write responses.
@@ -112,8 +175,10 @@ type API interface {
 	OpenAPI() *OpenAPI
 
 	// Negotiate returns the selected content type given the client's `accept`
-	// header and the server's supported content types. If the client does not
-	// send an `accept` header, then JSON is used.
+	// header and the server's supported content types. Media ranges like
+	// `application/*` are supported, and formats registered by suffix (e.g.
+	// `json`) match requested types like `application/vnd.foo+json`. If the
+	// client does not send an `accept` header, then the default format is used.
 	Negotiate(accept string) (string, error)
 
 	// Marshal marshals the given value into the given writer. The content type
@@ -123,6 +188,48 @@ type API interface {
 
 	// Unmarshal unmarshals the given data into the given value. The content type
 	Unmarshal(contentType string, data []byte, v any) error
+
+	// UseMiddleware appends middleware functions which are run for every
+	// operation registered via `huma.Register` after this call, before any
+	// operation-specific middleware. See `huma.Middlewares`.
+	UseMiddleware(middlewares ...func(ctx Context, next func(Context)))
+
+	// Middlewares returns the API-wide middleware chain.
+	Middlewares() Middlewares
+
+	// Config returns the configuration used to create this API.
+	Config() Config
+
+	// Logger returns the logger for this API. See `huma.RequestLogger` to
+	// include operation and request attributes.
+	Logger() *slog.Logger
+}
+
+// RequestLogger returns the API's logger with attributes describing the
+// operation and request attached, e.g. for logging from middleware:
+//
+//	huma.RequestLogger(api, ctx).Warn("deprecated operation called")
+func RequestLogger(api API, ctx Context) *slog.Logger {
+	attrs := make([]any, 0, 3)
+	if op := ctx.Operation(); op != nil && op.OperationID != "" {
+		attrs = append(attrs, slog.String("operationId", op.OperationID))
+	}
+	u := ctx.URL()
+	attrs = append(attrs, slog.String("method", ctx.Method()), slog.String("path", u.Path))
+	if extra, ok := ctx.Context().Value(logAttrsKey{}).([]any); ok {
+		attrs = append(attrs, extra...)
+	}
+	return api.Logger().With(attrs...)
+}
+
+type logAttrsKey struct{}
+
+// WithLogAttrs returns a copy of the context with additional attributes for
+// `huma.RequestLogger` to include, e.g. a request ID set by middleware. Use
+// `huma.WithContext` to set it for the rest of the request.
+func WithLogAttrs(ctx context.Context, attrs ...any) context.Context {
+	prev, _ := ctx.Value(logAttrsKey{}).([]any)
+	return context.WithValue(ctx, logAttrsKey{}, append(prev[:len(prev):len(prev)], attrs...))
 }
 
 // Format represents a request / response format. It is used to marshal and
@@ -141,6 +248,7 @@ type api struct {
 	formats      map[string]Format
 	formatKeys   []string
 	transformers []Transformer
+	middlewares  Middlewares
 }
 
 func (a *api) Adapter() Adapter {
@@ -151,6 +259,25 @@ func (a *api) OpenAPI() *OpenAPI {
 	return a.config.OpenAPI
 }
 
+func (a *api) UseMiddleware(middlewares ...func(ctx Context, next func(Context))) {
+	a.middlewares = append(a.middlewares, middlewares...)
+}
+
+func (a *api) Middlewares() Middlewares {
+	return a.middlewares
+}
+
+func (a *api) Config() Config {
+	return a.config
+}
+
+func (a *api) Logger() *slog.Logger {
+	if a.config.Logger != nil {
+		return a.config.Logger
+	}
+	return slog.Default()
+}
+
 func (a *api) Unmarshal(contentType string, data []byte, v any) error {
 	// Handle e.g. `application/json; charset=utf-8` or `my/format+json`
 	start := strings.IndexRune(contentType, '+') + 1
@@ -171,12 +298,15 @@ func (a *api) Unmarshal(contentType string, data []byte, v any) error {
 }
 
 func (a *api) Negotiate(accept string) (string, error) {
-	ct := negotiation.SelectQValueFast(accept, a.formatKeys)
+	ct := negotiation.SelectMediaType(accept, a.formatKeys)
 	if ct == "" && a.formatKeys != nil {
 		ct = a.formatKeys[0]
 	}
 	if _, ok := a.formats[ct]; !ok {
-		return ct, fmt.Errorf("unknown content type: %s", ct)
+		// Structured syntax suffix, e.g. `application/vnd.foo+json`.
+		if _, ok := a.formats[ct[strings.IndexRune(ct, '+')+1:]]; !ok || !strings.Contains(ct, "+") {
+			return ct, fmt.Errorf("unknown content type: %s", ct)
+		}
 	}
 	return ct, nil
 }
@@ -236,6 +366,10 @@ func NewAPI(config Config, a Adapter) API {
 		newAPI.formats[k] = v
 		newAPI.formatKeys = append(newAPI.formatKeys, k)
 	}
+	if config.IdempotencyStore == nil {
+		config.IdempotencyStore = NewMemoryIdempotencyStore(24 * time.Hour)
+	}
+	newAPI.config = config
 
 	if config.OpenAPIPath != "" {
 		var specJSON []byte
@@ -305,5 +439,14 @@ func NewAPI(config Config, a Adapter) API {
 		})
 	}
 
+	if config.ProblemsPath != "" {
+		a.Handle(&Operation{
+			Method: http.MethodGet,
+			Path:   config.ProblemsPath + "/{problem}",
+		}, func(ctx Context) {
+			Problems.servePage(ctx, ctx.Param("problem"))
+		})
+	}
+
 	return newAPI
 }
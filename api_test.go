@@ -1,7 +1,15 @@
 package huma
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
@@ -14,3 +22,147 @@ func TestBlankConfig(t *testing.T) {
 		NewAPI(Config{}, adapter)
 	})
 }
+
+func TestNegotiate(t *testing.T) {
+	api := NewTestAdapter(chi.NewMux(), DefaultConfig("Test API", "1.0.0"))
+
+	for _, item := range []struct {
+		accept   string
+		expected string
+	}{
+		{"", "application/json"},
+		{"*/*", "application/json"},
+		{"application/cbor", "application/cbor"},
+		{"application/vnd.foo+json", "application/vnd.foo+json"},
+		{"text/html, application/*;q=0.9", "application/json"},
+		{"text/html", "application/json"},
+	} {
+		ct, err := api.Negotiate(item.accept)
+		assert.NoError(t, err)
+		assert.Equal(t, item.expected, ct, item.accept)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	r := chi.NewRouter()
+	api := NewTestAdapter(r, DefaultConfig("Test API", "1.0.0"))
+
+	calls := []string{}
+	api.UseMiddleware(func(ctx Context, next func(Context)) {
+		calls = append(calls, "api1")
+		next(ctx)
+	}, func(ctx Context, next func(Context)) {
+		calls = append(calls, "api2")
+		next(ctx)
+	})
+
+	Register(api, Operation{
+		Method: http.MethodGet,
+		Path:   "/test",
+		Middlewares: Middlewares{func(ctx Context, next func(Context)) {
+			calls = append(calls, "op")
+			ctx.SetHeader("X-Middleware", "true")
+			next(ctx)
+		}},
+	}, func(ctx context.Context, input *struct{}) (*struct{}, error) {
+		calls = append(calls, "handler")
+		return nil, nil
+	})
+
+	Register(api, Operation{
+		Method: http.MethodGet,
+		Path:   "/short-circuit",
+		Middlewares: Middlewares{func(ctx Context, next func(Context)) {
+			ctx.SetStatus(http.StatusTeapot)
+		}},
+	}, func(ctx context.Context, input *struct{}) (*struct{}, error) {
+		t.Fatal("handler should not be called")
+		return nil, nil
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/test", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "true", w.Header().Get("X-Middleware"))
+	assert.Equal(t, []string{"api1", "api2", "op", "handler"}, calls)
+
+	req, _ = http.NewRequest(http.MethodGet, "/short-circuit", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusTeapot, w.Code)
+}
+
+func TestLogger(t *testing.T) {
+	api := NewTestAdapter(chi.NewMux(), DefaultConfig("Test API", "1.0.0"))
+	assert.Equal(t, slog.Default(), api.Logger())
+
+	logs := &bytes.Buffer{}
+	config := DefaultConfig("Test API", "1.0.0")
+	config.Logger = slog.New(slog.NewTextHandler(logs, nil))
+	config.Transformers = append(config.Transformers, func(ctx Context, status string, v any) (any, error) {
+		return nil, errors.New("transform failed")
+	})
+	r := chi.NewRouter()
+	api = NewTestAdapter(r, config)
+	assert.Equal(t, config.Logger, api.Logger())
+	api.UseMiddleware(func(ctx Context, next func(Context)) {
+		next(WithContext(ctx, WithLogAttrs(ctx.Context(), "requestId", "abc123")))
+	})
+
+	Register(api, Operation{
+		OperationID: "get-broken",
+		Method:      http.MethodGet,
+		Path:        "/broken",
+	}, func(ctx context.Context, input *struct{}) (*struct{ Body string }, error) {
+		return &struct{ Body string }{Body: "hello"}, nil
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/broken", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	// The status was already sent when marshaling failed, so it is logged.
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, logs.String(), `level=ERROR msg="unable to marshal response" operationId=get-broken method=GET path=/broken requestId=abc123 status=200 error="transform failed"`)
+}
+
+func TestLoggerReadDeadline(t *testing.T) {
+	logs := &bytes.Buffer{}
+	config := DefaultConfig("Test API", "1.0.0")
+	config.Logger = slog.New(slog.NewTextHandler(logs, nil))
+	r := chi.NewRouter()
+	api := NewTestAdapter(r, config)
+
+	Register(api, Operation{
+		Method:          http.MethodPut,
+		Path:            "/body",
+		BodyReadTimeout: time.Second,
+	}, func(ctx context.Context, input *struct{ Body string }) (*struct{}, error) {
+		return nil, nil
+	})
+
+	// The recorder does not support deadlines, which is too common to warn
+	// about on every request.
+	req, _ := http.NewRequest(http.MethodPut, "/body", strings.NewReader(`"hello"`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, logs.String())
+
+	config.Logger = slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	r = chi.NewRouter()
+	api = NewTestAdapter(r, config)
+	Register(api, Operation{
+		Method:          http.MethodPut,
+		Path:            "/body",
+		BodyReadTimeout: time.Second,
+	}, func(ctx context.Context, input *struct{ Body string }) (*struct{}, error) {
+		return nil, nil
+	})
+	req, _ = http.NewRequest(http.MethodPut, "/body", strings.NewReader(`"hello"`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Contains(t, logs.String(), `level=DEBUG msg="unable to set read deadline"`)
+}
@@ -0,0 +1,359 @@
+// Package asyncapi generates an AsyncAPI 3.0 document describing the
+// streaming operations registered via the `sse` and `websocket` packages,
+// and serves it alongside the OpenAPI document.
+//
+//	// Later in the code *after* registering operations...
+//	asyncapi.Serve(api, asyncapi.Options{})
+package asyncapi
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/sse"
+	"github.com/danielgtaylor/huma/v2/websocket"
+	"github.com/goccy/go-yaml"
+)
+
+// Ref is a reference to another object in the document.
+type Ref struct {
+	Ref string `yaml:"$ref"`
+}
+
+// Server describes a host which serves the API.
+type Server struct {
+	Host        string `yaml:"host"`
+	Protocol    string `yaml:"protocol"`
+	Pathname    string `yaml:"pathname,omitempty"`
+	Description string `yaml:"description,omitempty"`
+
+	// Extensions (user-defined properties), if any. Values in this map will
+	// be marshalled as siblings of the other properties above.
+	Extensions map[string]any `yaml:",inline"`
+}
+
+// Message describes a single message sent over a channel.
+type Message struct {
+	Name        string       `yaml:"name,omitempty"`
+	Title       string       `yaml:"title,omitempty"`
+	Summary     string       `yaml:"summary,omitempty"`
+	Description string       `yaml:"description,omitempty"`
+	ContentType string       `yaml:"contentType,omitempty"`
+	Payload     *huma.Schema `yaml:"payload,omitempty"`
+
+	// Extensions (user-defined properties), if any. Values in this map will
+	// be marshalled as siblings of the other properties above.
+	Extensions map[string]any `yaml:",inline"`
+}
+
+// Parameter describes a parameter in a channel address.
+type Parameter struct {
+	Description string   `yaml:"description,omitempty"`
+	Enum        []string `yaml:"enum,omitempty"`
+	Default     string   `yaml:"default,omitempty"`
+	Examples    []string `yaml:"examples,omitempty"`
+}
+
+// Channel is an addressable component, e.g. an SSE or WebSocket endpoint,
+// over which messages are sent.
+type Channel struct {
+	Address     string                `yaml:"address"`
+	Title       string                `yaml:"title,omitempty"`
+	Summary     string                `yaml:"summary,omitempty"`
+	Description string                `yaml:"description,omitempty"`
+	Messages    map[string]*Message   `yaml:"messages,omitempty"`
+	Parameters  map[string]*Parameter `yaml:"parameters,omitempty"`
+	Bindings    map[string]any        `yaml:"bindings,omitempty"`
+
+	// Extensions (user-defined properties), if any. Values in this map will
+	// be marshalled as siblings of the other properties above.
+	Extensions map[string]any `yaml:",inline"`
+}
+
+// Operation describes what the application does on a channel. The `send`
+// action means the server sends messages to clients, while `receive` means
+// the server receives messages from clients.
+type Operation struct {
+	Action      string `yaml:"action"`
+	Channel     *Ref   `yaml:"channel"`
+	Title       string `yaml:"title,omitempty"`
+	Summary     string `yaml:"summary,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	Tags        []*Tag `yaml:"tags,omitempty"`
+	Messages    []*Ref `yaml:"messages,omitempty"`
+
+	// Extensions (user-defined properties), if any. Values in this map will
+	// be marshalled as siblings of the other properties above.
+	Extensions map[string]any `yaml:",inline"`
+}
+
+// Tag is a label for grouping operations.
+type Tag struct {
+	Name string `yaml:"name"`
+}
+
+// Components holds reusable objects referenced from the rest of the
+// document. Schemas are shared with the OpenAPI document's registry.
+type Components struct {
+	Schemas map[string]*huma.Schema `yaml:"schemas,omitempty"`
+}
+
+// AsyncAPI is the root of an AsyncAPI 3.0 document.
+type AsyncAPI struct {
+	AsyncAPI           string                `yaml:"asyncapi"`
+	Info               *huma.Info            `yaml:"info"`
+	Servers            map[string]*Server    `yaml:"servers,omitempty"`
+	DefaultContentType string                `yaml:"defaultContentType,omitempty"`
+	Channels           map[string]*Channel   `yaml:"channels,omitempty"`
+	Operations         map[string]*Operation `yaml:"operations,omitempty"`
+	Components         *Components           `yaml:"components,omitempty"`
+
+	// Extensions (user-defined properties), if any. Values in this map will
+	// be marshalled as siblings of the other properties above.
+	Extensions map[string]any `yaml:",inline"`
+}
+
+// MarshalJSON marshals the document as JSON. Like the OpenAPI document, this
+// goes through the YAML marshaller to support the `,inline` extensions.
+func (a *AsyncAPI) MarshalJSON() ([]byte, error) {
+	return yaml.MarshalWithOptions(a, yaml.JSON())
+}
+
+// escape a channel name for use in a JSON Pointer.
+func escape(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
+
+// channelName returns a name for the operation's channel.
+func channelName(op *huma.Operation) string {
+	if op.OperationID != "" {
+		return op.OperationID
+	}
+	return strings.ToLower(op.Method) + strings.NewReplacer("/", "-", "{", "", "}", "").Replace(op.Path)
+}
+
+// newChannel creates a channel for the operation.
+func newChannel(op *huma.Operation) *Channel {
+	ch := &Channel{
+		Address:     op.Path,
+		Summary:     op.Summary,
+		Description: op.Description,
+		Messages:    map[string]*Message{},
+	}
+	for _, p := range op.Parameters {
+		if p.In != "path" {
+			continue
+		}
+		if ch.Parameters == nil {
+			ch.Parameters = map[string]*Parameter{}
+		}
+		desc := p.Description
+		if desc == "" && p.Schema != nil {
+			desc = p.Schema.Description
+		}
+		ch.Parameters[p.Name] = &Parameter{Description: desc}
+	}
+	return ch
+}
+
+// newOperation creates an operation for the channel with the given messages.
+func newOperation(op *huma.Operation, action, channel string, messages ...string) *Operation {
+	o := &Operation{
+		Action:      action,
+		Channel:     &Ref{Ref: "#/channels/" + escape(channel)},
+		Summary:     op.Summary,
+		Description: op.Description,
+	}
+	for _, tag := range op.Tags {
+		o.Tags = append(o.Tags, &Tag{Name: tag})
+	}
+	for _, m := range messages {
+		o.Messages = append(o.Messages, &Ref{Ref: "#/channels/" + escape(channel) + "/messages/" + escape(m)})
+	}
+	return o
+}
+
+// sortedKeys returns the keys of a map in sorted order so output is stable.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// New generates an AsyncAPI document from the streaming operations registered
+// on the API. It should be called *after* all operations are registered.
+func New(api huma.API) *AsyncAPI {
+	oapi := api.OpenAPI()
+	ct := "application/json"
+	if c, err := api.Negotiate(""); err == nil && c != "" {
+		ct = c
+	}
+
+	doc := &AsyncAPI{
+		AsyncAPI:           "3.0.0",
+		Info:               oapi.Info,
+		DefaultContentType: ct,
+		Channels:           map[string]*Channel{},
+		Operations:         map[string]*Operation{},
+		Components: &Components{
+			Schemas: oapi.Components.Schemas.Map(),
+		},
+	}
+	if doc.Info == nil {
+		doc.Info = &huma.Info{}
+	}
+
+	for i, s := range oapi.Servers {
+		u, err := url.Parse(s.URL)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		if doc.Servers == nil {
+			doc.Servers = map[string]*Server{}
+		}
+		name := "server"
+		if i > 0 {
+			name += strconv.Itoa(i + 1)
+		}
+		doc.Servers[name] = &Server{
+			Host:        u.Host,
+			Protocol:    u.Scheme,
+			Pathname:    strings.TrimSuffix(u.Path, "/"),
+			Description: s.Description,
+		}
+	}
+
+	for _, path := range sortedKeys(oapi.Paths) {
+		item := oapi.Paths[path]
+		for _, op := range []*huma.Operation{item.Get, item.Put, item.Post, item.Patch, item.Delete, item.Head, item.Options, item.Trace} {
+			if op == nil {
+				continue
+			}
+
+			if events, ok := op.Metadata[sse.MetadataKey].(map[string]*huma.Schema); ok {
+				name := channelName(op)
+				ch := newChannel(op)
+				contentTypes, _ := op.Metadata[sse.ContentTypesMetadataKey].(map[string]string)
+				for _, event := range sortedKeys(events) {
+					// SSE event data is JSON unless configured otherwise,
+					// regardless of the API's default format.
+					eventCT := contentTypes[event]
+					if eventCT == "" {
+						eventCT = "application/json"
+					}
+					ch.Messages[event] = &Message{
+						Name:        event,
+						ContentType: eventCT,
+						Payload:     events[event],
+					}
+				}
+				doc.Channels[name] = ch
+				doc.Operations[name] = newOperation(op, "send", name, sortedKeys(events)...)
+			}
+
+			if messages, ok := op.Metadata[websocket.MetadataKey].(map[string]*huma.Schema); ok {
+				name := channelName(op)
+				ch := newChannel(op)
+				ch.Bindings = map[string]any{
+					"ws": map[string]any{
+						"method":         op.Method,
+						"bindingVersion": "0.1.0",
+					},
+				}
+				for _, m := range sortedKeys(messages) {
+					ch.Messages[m] = &Message{
+						Name:    m,
+						Payload: messages[m],
+					}
+				}
+				doc.Channels[name] = ch
+				doc.Operations[name+"-receive"] = newOperation(op, "receive", name, "clientMessage")
+				doc.Operations[name+"-send"] = newOperation(op, "send", name, "serverMessage")
+			}
+		}
+	}
+
+	return doc
+}
+
+// Options configures how the AsyncAPI document is served.
+type Options struct {
+	// Path to the AsyncAPI document without extension. Both `.json` and
+	// `.yaml` are served. Defaults to `/asyncapi`.
+	Path string
+
+	// DocsPath, if set, serves an HTML page rendering the AsyncAPI document.
+	DocsPath string
+}
+
+// Serve registers handlers which serve the AsyncAPI document for the API.
+// The document is generated on the first request, so streaming operations
+// registered after calling this are included.
+func Serve(api huma.API, opts Options) {
+	if opts.Path == "" {
+		opts.Path = "/asyncapi"
+	}
+	adapter := api.Adapter()
+
+	// The documents are built on first use since operations are usually
+	// registered after calling `Serve`.
+	var jsonOnce, yamlOnce sync.Once
+	var specJSON, specYAML []byte
+	adapter.Handle(&huma.Operation{
+		Method: http.MethodGet,
+		Path:   opts.Path + ".json",
+	}, func(ctx huma.Context) {
+		ctx.SetHeader("Content-Type", "application/json")
+		jsonOnce.Do(func() {
+			specJSON, _ = New(api).MarshalJSON()
+		})
+		ctx.BodyWriter().Write(specJSON)
+	})
+
+	adapter.Handle(&huma.Operation{
+		Method: http.MethodGet,
+		Path:   opts.Path + ".yaml",
+	}, func(ctx huma.Context) {
+		ctx.SetHeader("Content-Type", "application/yaml")
+		yamlOnce.Do(func() {
+			specYAML, _ = yaml.Marshal(New(api))
+		})
+		ctx.BodyWriter().Write(specYAML)
+	})
+
+	if opts.DocsPath != "" {
+		adapter.Handle(&huma.Operation{
+			Method: http.MethodGet,
+			Path:   opts.DocsPath,
+		}, func(ctx huma.Context) {
+			ctx.SetHeader("Content-Type", "text/html")
+			ctx.BodyWriter().Write([]byte(`<!doctype html>
+<html lang="en">
+  <head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1, shrink-to-fit=no">
+    <title>AsyncAPI Docs</title>
+    <link rel="stylesheet" href="https://unpkg.com/@asyncapi/react-component/styles/default.min.css">
+  </head>
+  <body>
+    <div id="asyncapi"></div>
+    <script src="https://unpkg.com/@asyncapi/react-component/browser/standalone/index.js"></script>
+    <script>
+      AsyncApiStandalone.render({
+        schema: { url: "` + opts.Path + `.yaml" },
+        config: { show: { sidebar: true } },
+      }, document.getElementById("asyncapi"));
+    </script>
+  </body>
+</html>`))
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package asyncapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/danielgtaylor/huma/v2/sse"
+	"github.com/danielgtaylor/huma/v2/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type StatusEvent struct {
+	Status string `json:"status"`
+}
+
+type AlertEvent struct {
+	Message string `json:"message"`
+}
+
+type ChatMessage struct {
+	Text string `json:"text"`
+}
+
+type ChatReply struct {
+	Echo string `json:"echo"`
+}
+
+func TestAsyncAPI(t *testing.T) {
+	_, api := humatest.New(t)
+	api.OpenAPI().Servers = []*huma.Server{{URL: "https://api.example.com/v1"}}
+
+	Serve(api, Options{DocsPath: "/asyncapi-docs"})
+
+	sse.Register(api, huma.Operation{
+		OperationID: "status",
+		Method:      http.MethodGet,
+		Path:        "/status/{region}",
+		Summary:     "Status updates",
+		Tags:        []string{"Status"},
+	}, map[string]any{
+		"status": StatusEvent{},
+		"alert":  AlertEvent{},
+	}, func(ctx context.Context, input *struct {
+		Region string `path:"region" doc:"Region name"`
+	}, send sse.Sender) {
+	}, sse.Options{
+		ContentTypes: map[string]string{"alert": "text/plain"},
+	})
+
+	websocket.Register(api, huma.Operation{
+		OperationID: "chat",
+		Path:        "/chat",
+	}, func(ctx context.Context, input *struct{}, conn *websocket.Conn[ChatMessage, ChatReply]) {
+	})
+
+	// Regular operations are not included.
+	huma.Register(api, huma.Operation{
+		OperationID: "get-thing",
+		Method:      http.MethodGet,
+		Path:        "/thing",
+	}, func(ctx context.Context, input *struct{}) (*struct{}, error) {
+		return nil, nil
+	})
+
+	resp := api.Get("/asyncapi.json")
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.0", doc["asyncapi"])
+	assert.Equal(t, "Test API", doc["info"].(map[string]any)["title"])
+	assert.Equal(t, "application/json", doc["defaultContentType"])
+	assert.Equal(t, map[string]any{
+		"host":     "api.example.com",
+		"protocol": "https",
+		"pathname": "/v1",
+	}, doc["servers"].(map[string]any)["server"])
+
+	channels := doc["channels"].(map[string]any)
+	assert.Len(t, channels, 2)
+
+	status := channels["status"].(map[string]any)
+	assert.Equal(t, "/status/{region}", status["address"])
+	assert.Equal(t, "Region name", status["parameters"].(map[string]any)["region"].(map[string]any)["description"])
+	alert := status["messages"].(map[string]any)["alert"].(map[string]any)
+	assert.Equal(t, "alert", alert["name"])
+	assert.Equal(t, "#/components/schemas/AlertEvent", alert["payload"].(map[string]any)["$ref"])
+	assert.Equal(t, "text/plain", alert["contentType"])
+	assert.Equal(t, "application/json", status["messages"].(map[string]any)["status"].(map[string]any)["contentType"])
+
+	chat := channels["chat"].(map[string]any)
+	assert.Equal(t, "GET", chat["bindings"].(map[string]any)["ws"].(map[string]any)["method"])
+	client := chat["messages"].(map[string]any)["clientMessage"].(map[string]any)
+	assert.Equal(t, "#/components/schemas/ChatMessage", client["payload"].(map[string]any)["$ref"])
+
+	operations := doc["operations"].(map[string]any)
+	assert.Len(t, operations, 3)
+	assert.Equal(t, map[string]any{
+		"action":  "send",
+		"channel": map[string]any{"$ref": "#/channels/status"},
+		"summary": "Status updates",
+		"tags":    []any{map[string]any{"name": "Status"}},
+		"messages": []any{
+			map[string]any{"$ref": "#/channels/status/messages/alert"},
+			map[string]any{"$ref": "#/channels/status/messages/status"},
+		},
+	}, operations["status"])
+	assert.Equal(t, "receive", operations["chat-receive"].(map[string]any)["action"])
+	assert.Equal(t, "send", operations["chat-send"].(map[string]any)["action"])
+
+	schemas := doc["components"].(map[string]any)["schemas"].(map[string]any)
+	assert.Contains(t, schemas, "StatusEvent")
+	assert.Contains(t, schemas, "ChatReply")
+
+	resp = api.Get("/asyncapi.yaml")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), "asyncapi: 3.0.0")
+
+	resp = api.Get("/asyncapi-docs")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `url: "/asyncapi.yaml"`)
+}
+
+func TestServeConcurrent(t *testing.T) {
+	r, api := humatest.New(t)
+	Serve(api, Options{})
+
+	// The documents are built once even when first requested concurrently.
+	// Requests go straight to the router since logging them via the test API
+	// would serialize them.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		for _, path := range []string{"/asyncapi.json", "/asyncapi.yaml"} {
+			wg.Add(1)
+			go func(path string) {
+				defer wg.Done()
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+				assert.Equal(t, http.StatusOK, w.Code)
+				assert.Contains(t, w.Body.String(), "asyncapi")
+			}(path)
+		}
+	}
+	wg.Wait()
+}
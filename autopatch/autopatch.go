@@ -3,15 +3,17 @@ package autopatch
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
-	"net/http/httptest"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/danielgtaylor/casing"
 	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/conditional"
 	"github.com/danielgtaylor/shorthand/v2"
 	jsonpatch "github.com/evanphx/json-patch/v5"
 )
@@ -27,22 +29,125 @@ type jsonPatchOp struct {
 
 var jsonPatchType = reflect.TypeOf([]jsonPatchOp{})
 
+// ExtensionKey is the OpenAPI operation extension used to opt in or out of
+// PATCH generation. Set it to `false` on the GET or PUT operation to skip a
+// resource, or to `true` to generate a PATCH even if the GET response and PUT
+// request body schemas do not appear to be compatible.
+//
+//	huma.Register(api, huma.Operation{
+//		OperationID: "put-thing",
+//		Method:      http.MethodPut,
+//		Path:        "/things/{id}",
+//		Extensions: map[string]any{
+//			autopatch.ExtensionKey: false,
+//		},
+//	}, handler)
+const ExtensionKey = "x-autopatch"
+
 // AutoPatch generates HTTP PATCH operations for any resource which has a
-// GET & PUT but no pre-existing PATCH operation. Generated PATCH operations
-// will call GET, apply either `application/merge-patch+json` or
-// `application/json-patch+json` patches, then call PUT with the updated
-// resource. This method may be safely called multiple times.
+// GET & PUT registered via `huma.Register` but no pre-existing PATCH
+// operation. Generated PATCH operations will call the GET handler, apply
+// either `application/merge-patch+json` or `application/json-patch+json`
+// patches, then call the PUT handler with the updated resource. Handlers are
+// called in-process with the same input parsing, validation, and resolvers as
+// normal requests. This method may be safely called multiple times.
+//
+// The GET response body and PUT request body must be compatible objects for
+// a PATCH to be generated, otherwise the resource is skipped with a warning.
+// See `ExtensionKey` to override this behavior per operation.
 func AutoPatch(api huma.API) {
 	oapi := api.OpenAPI()
 	for _, path := range oapi.Paths {
-		if path.Get != nil && path.Put != nil && path.Patch == nil {
-			// TODO: ensure that the GET & PUT operations are for the same resource
-			// and it is a struct.
+		if path.Get != nil && path.Put != nil && path.Patch == nil && path.Get.Handler() != nil && path.Put.Handler() != nil {
+			optIn, optOut := extensionOpt(path.Get)
+			putIn, putOut := extensionOpt(path.Put)
+			if optOut || putOut {
+				continue
+			}
+			if !optIn && !putIn {
+				if err := compatible(oapi.Components.Schemas, path.Get, path.Put); err != nil {
+					api.Logger().Warn("autopatch skipping incompatible resource", "path", path.Put.Path, "error", err)
+					continue
+				}
+			}
 			generatePatch(api, path)
 		}
 	}
 }
 
+// extensionOpt returns whether an operation has explicitly opted in or out of
+// PATCH generation.
+func extensionOpt(op *huma.Operation) (optIn, optOut bool) {
+	if v, ok := op.Extensions[ExtensionKey].(bool); ok {
+		return v, !v
+	}
+	return false, false
+}
+
+// bodySchema returns the resolved JSON schema from a content map.
+func bodySchema(registry huma.Registry, content map[string]*huma.MediaType) *huma.Schema {
+	mt := content["application/json"]
+	if mt == nil || mt.Schema == nil {
+		return nil
+	}
+	if mt.Schema.Ref != "" {
+		return registry.SchemaFromRef(mt.Schema.Ref)
+	}
+	return mt.Schema
+}
+
+// compatible checks that the GET response body and PUT request body describe
+// the same resource, so that a patched GET response is a valid PUT body.
+// Every PUT property must be returned by the GET with the same type, and if
+// the PUT disallows additional properties then the GET must not return any
+// properties the PUT does not accept.
+func compatible(registry huma.Registry, get, put *huma.Operation) error {
+	// Use the 200 response, or the lowest 2xx response if there is none, so
+	// the result does not depend on map iteration order.
+	var getSchema, putSchema *huma.Schema
+	success := ""
+	for code := range get.Responses {
+		if len(code) == 3 && code[0] == '2' && (success == "" || code < success) {
+			success = code
+		}
+	}
+	if success != "" {
+		getSchema = bodySchema(registry, get.Responses[success].Content)
+	}
+	if put.RequestBody != nil {
+		putSchema = bodySchema(registry, put.RequestBody.Content)
+	}
+	if getSchema == nil || putSchema == nil {
+		return fmt.Errorf("missing GET response or PUT request body schema")
+	}
+	if getSchema == putSchema {
+		return nil
+	}
+	if getSchema.Type != huma.TypeObject || putSchema.Type != huma.TypeObject {
+		return fmt.Errorf("GET response and PUT request bodies must be objects")
+	}
+
+	for name, prop := range putSchema.Properties {
+		getProp := getSchema.Properties[name]
+		if getProp == nil {
+			return fmt.Errorf("PUT property %s is missing from GET response", name)
+		}
+		if prop.Type != getProp.Type || prop.Ref != getProp.Ref {
+			return fmt.Errorf("PUT property %s does not match the GET response type", name)
+		}
+	}
+
+	if putSchema.AdditionalProperties == false {
+		for name := range getSchema.Properties {
+			if putSchema.Properties[name] == nil && name != "$schema" {
+				return fmt.Errorf("GET property %s is not accepted by PUT", name)
+			}
+		}
+	}
+
+	return nil
+}
+
 // generatePatch is called for each resource which needs a PATCH operation to
 // be added. it registers and provides a handler for this new operation.
 func generatePatch(api huma.API, path *huma.PathItem) {
@@ -66,29 +171,43 @@ func generatePatch(api huma.API, path *huma.PathItem) {
 		responses[k] = v
 	}
 	statuses := append([]int{}, put.Errors...)
+	codes := []int{http.StatusPreconditionFailed}
 	if responses["default"] == nil {
-		for _, code := range []int{
+		codes = append(codes,
 			http.StatusNotModified,
 			http.StatusBadRequest,
+			http.StatusConflict,
 			http.StatusUnprocessableEntity,
 			http.StatusUnsupportedMediaType,
-		} {
-			found := false
-			for statusStr := range put.Responses {
-				if statusStr == strconv.Itoa(code) {
-					found = true
-					break
-				}
+		)
+	}
+	for _, code := range codes {
+		found := false
+		for statusStr := range put.Responses {
+			if statusStr == strconv.Itoa(code) {
+				found = true
+				break
 			}
-			for status := range put.Errors {
-				if status == code {
-					found = true
-					break
-				}
+		}
+		for _, status := range put.Errors {
+			if status == code {
+				found = true
+				break
 			}
-			if !found {
-				statuses = append(statuses, code)
+		}
+		if !found {
+			statuses = append(statuses, code)
+			resp := &huma.Response{Description: http.StatusText(code)}
+			if code != http.StatusNotModified {
+				// Errors share the same content as any existing error response.
+				for _, errResp := range []string{"default", "422", "500"} {
+					if r := put.Responses[errResp]; r != nil {
+						resp.Content = r.Content
+						break
+					}
+				}
 			}
+			responses[strconv.Itoa(code)] = resp
 		}
 	}
 
@@ -133,87 +252,86 @@ func generatePatch(api huma.API, path *huma.PathItem) {
 	}
 	oapi.AddOperation(op)
 
-	// Manually register the handler with the router. This bypasses the normal
-	// Huma API since this is easier and we are just calling the other pre-existing
-	// operations.
-	adapter := api.Adapter()
-	adapter.Handle(op, func(ctx huma.Context) {
-		patchData, err := io.ReadAll(ctx.BodyReader())
-		if err != nil {
-			huma.WriteErr(api, ctx, http.StatusBadRequest, "Unable to read request body", err)
+	// Register the handler with the router. The GET & PUT operation handlers
+	// are called directly in-process, so the incoming request's context (e.g.
+	// auth info) is preserved and router middleware is not run again. API-wide
+	// and PUT operation middleware runs once for the PATCH itself.
+	op.Middlewares = put.Middlewares
+	op.Metadata = put.Metadata
+	getHandler := get.Handler()
+	putHandler := put.Handler()
+	api.Adapter().Handle(op, api.Middlewares().Handler(op.Middlewares.Handler(func(ctx huma.Context) {
+		// The internal PUT is sent the GET's ETag, so enforce a PUT configured
+		// with `conditional.Require` here or the PATCH could blindly overwrite.
+		cond := preconditions(ctx)
+		if err := cond.PreconditionRequired(); err != nil {
+			writeErr(api, ctx, err)
 			return
 		}
 
-		// Perform the get!
-		origReq, err := http.NewRequest(http.MethodGet, ctx.URL().Path, nil)
+		patchData, err := io.ReadAll(ctx.BodyReader())
 		if err != nil {
-			huma.WriteErr(api, ctx, http.StatusInternalServerError, "Unable to get resource", err)
+			huma.WriteErr(api, ctx, http.StatusBadRequest, "Unable to read request body", err)
 			return
 		}
 
-		// Copy incoming headers.
-		ctx.EachHeader(func(k, v string) {
-			if k == "Accept" || k == "Accept-Encoding" {
-				// We will force these to be JSON for easier handling.
-				return
-			}
-			if k == "If-Match" || k == "If-None-Match" || k == "If-Modified-Since" || k == "If-Unmodified-Since" {
-				// Conditional request headers will be used on the write side, so
-				// ignore them here.
-				return
-			}
-			if k == "Content-Type" || k == "Content-Length" {
-				// GET will be empty.
-				return
-			}
-			origReq.Header.Add(k, v)
-		})
+		// Perform the get! Prefer JSON for the patches, but fall back to the
+		// API's default format, e.g. for CBOR-only APIs.
+		resourceType, _ := api.Negotiate("application/json")
+		origCtx := newSubContext(ctx, get, http.MethodGet, nil)
+		origCtx.headers.Set("Accept", resourceType)
+		getHandler(origCtx)
 
-		// Accept JSON for the patches.
-		// TODO: could we accept other stuff here...?
-		ctx.SetHeader("Accept", "application/json")
-		ctx.SetHeader("Accept-Encoding", "")
+		if origCtx.status >= 300 {
+			// This represents an error on the GET side.
+			origCtx.writeTo(ctx)
+			return
+		}
 
-		origWriter := httptest.NewRecorder()
-		adapter.ServeHTTP(origWriter, origReq)
+		// Check the client's preconditions against the current version of the
+		// resource before modifying it, so concurrent patches cannot clobber
+		// each other's changes.
+		oh := origCtx.respHeaders
+		etag := oh.Get("ETag")
+		var modified time.Time
+		if lm := oh.Get("Last-Modified"); lm != "" {
+			modified, _ = http.ParseTime(lm)
+		}
+		if err := cond.PreconditionFailed(trimETag(etag), modified); err != nil {
+			writeErr(api, ctx, err)
+			return
+		}
 
-		if origWriter.Code >= 300 {
-			// This represents an error on the GET side.
-			for key, values := range origWriter.Header() {
-				for _, value := range values {
-					ctx.SetHeader(key, value)
-				}
-			}
-			ctx.SetStatus(origWriter.Code)
-			io.Copy(ctx.BodyWriter(), origWriter.Body)
+		// Patch the data! Patches are applied to the JSON representation of the
+		// resource.
+		orig, err := toJSON(api, oh.Get("Content-Type"), origCtx.respBody.Bytes())
+		if err != nil {
+			huma.WriteErr(api, ctx, http.StatusInternalServerError, "Unable to decode resource", err)
 			return
 		}
 
-		// Patch the data!
 		var patched []byte
-		switch strings.Split(ctx.Header("Content-Type"), ";")[0] {
-		case "application/json-patch+json":
-			patch, err := jsonpatch.DecodePatch(patchData)
-			if err != nil {
-				huma.WriteErr(api, ctx, http.StatusUnprocessableEntity, "Unable to decode JSON Patch", err)
+		var jsonPatch jsonpatch.Patch
+		patchType := strings.TrimSpace(strings.Split(ctx.Header("Content-Type"), ";")[0])
+		switch {
+		case patchType == "application/json-patch+json":
+			var se huma.StatusError
+			patched, jsonPatch, se = applyJSONPatch(orig, patchData)
+			if se != nil {
+				writeErr(api, ctx, se)
 				return
 			}
-			patched, err = patch.Apply(origWriter.Body.Bytes())
-			if err != nil {
-				huma.WriteErr(api, ctx, http.StatusUnprocessableEntity, "Unable to apply patch", err)
-				return
-			}
-		case "application/merge-patch+json", "application/json", "":
+		case patchType == "application/merge-patch+json", patchType == "application/json", patchType == "":
 			// Assume most cases are merge-patch.
-			patched, err = jsonpatch.MergePatch(origWriter.Body.Bytes(), patchData)
+			patched, err = jsonpatch.MergePatch(orig, patchData)
 			if err != nil {
 				huma.WriteErr(api, ctx, http.StatusUnprocessableEntity, "Unable to apply patch", err)
 				return
 			}
-		case "application/merge-patch+shorthand":
+		case patchType == "application/merge-patch+shorthand":
 			// Load the original data so it can be used as a base.
 			var tmp any
-			if err := json.Unmarshal(origWriter.Body.Bytes(), &tmp); err != nil {
+			if err := json.Unmarshal(orig, &tmp); err != nil {
 				huma.WriteErr(api, ctx, http.StatusUnprocessableEntity, "Unable to apply patch", err)
 				return
 			}
@@ -233,55 +351,213 @@ func generatePatch(api huma.API, path *huma.PathItem) {
 				huma.WriteErr(api, ctx, http.StatusUnprocessableEntity, "Unable to apply patch", err)
 				return
 			}
+		case strings.HasPrefix(patchType, "application/merge-patch+"):
+			// A merge patch in any other configured format, e.g. CBOR.
+			patchJSON, err := toJSON(api, patchType, patchData)
+			if err != nil {
+				huma.WriteErr(api, ctx, http.StatusUnprocessableEntity, "Unable to decode merge patch", err)
+				return
+			}
+			patched, err = jsonpatch.MergePatch(orig, patchJSON)
+			if err != nil {
+				huma.WriteErr(api, ctx, http.StatusUnprocessableEntity, "Unable to apply patch", err)
+				return
+			}
 		default:
 			// A content type we explicitly do not support was passed.
-			huma.WriteErr(api, ctx, http.StatusUnsupportedMediaType, "Content type should be one of application/merge-patch+json or application/json-patch+json", nil)
+			huma.WriteErr(api, ctx, http.StatusUnsupportedMediaType, "Content type should be one of application/merge-patch+json, application/merge-patch+shorthand, or application/json-patch+json")
 			return
 		}
 
-		if bytes.Equal(bytes.TrimSpace(patched), bytes.TrimSpace(origWriter.Body.Bytes())) {
+		if bytes.Equal(bytes.TrimSpace(patched), bytes.TrimSpace(orig)) {
+			// Nothing changed, so the current version is still valid.
+			if etag != "" {
+				ctx.SetHeader("ETag", etag)
+			}
+			if lm := oh.Get("Last-Modified"); lm != "" {
+				ctx.SetHeader("Last-Modified", lm)
+			}
 			ctx.SetStatus(http.StatusNotModified)
 			return
 		}
 
-		// Write the updated data back to the server!
-		putReq, err := http.NewRequest(http.MethodPut, op.Path, bytes.NewReader(patched))
+		// Write the updated data back to the server in the same format as it was
+		// read! The client's preconditions were already checked above, so they
+		// are not passed along.
+		body, err := fromJSON(api, newSubContext(ctx, put, http.MethodPut, nil), resourceType, patched)
 		if err != nil {
-			huma.WriteErr(api, ctx, http.StatusInternalServerError, "Unable to put modified resource", err)
+			huma.WriteErr(api, ctx, http.StatusInternalServerError, "Unable to encode resource", err)
 			return
 		}
-		ctx.EachHeader(func(k, v string) {
-			if k == "Content-Type" || k == "Content-Length" {
-				return
-			}
-			putReq.Header.Add(k, v)
-		})
-
-		putReq.Header.Set("Content-Type", "application/json")
-
-		h := putReq.Header
-		if h.Get("If-Match") == "" && h.Get("If-None-Match") == "" && h.Get("If-Unmodified-Since") == "" && h.Get("If-Modified-Since") == "" {
-			// No conditional headers have been set on the request. Can we set one?
-			// If we have an ETag or last modified time then we can set a corresponding
-			// conditional request header to prevent overwriting someone else's
-			// changes between when we did our GET and are doing our PUT.
-			// Distributed write failures will result in a 412 Precondition Failed.
-			oh := origWriter.Header()
-			if etag := oh.Get("ETag"); etag != "" {
-				h.Set("If-Match", etag)
-			} else if modified := oh.Get("Last-Modified"); modified != "" {
-				h.Set("If-Unmodified-Since", modified)
-			}
+		putCtx := newSubContext(ctx, put, http.MethodPut, body)
+		putCtx.headers.Set("Content-Type", resourceType)
+		if accept := ctx.Header("Accept"); accept != "" {
+			putCtx.headers.Set("Accept", accept)
+		}
+
+		// If we have an ETag or last modified time then we set a corresponding
+		// conditional request header to prevent overwriting someone else's
+		// changes between when we did our GET and are doing our PUT, making the
+		// read-modify-write atomic if the PUT supports conditional requests.
+		// Distributed write failures will result in a 412 Precondition Failed.
+		if etag != "" {
+			putCtx.headers.Set("If-Match", etag)
+		} else if lm := oh.Get("Last-Modified"); lm != "" {
+			putCtx.headers.Set("If-Unmodified-Since", lm)
+		}
+
+		putHandler(putCtx)
+
+		if putCtx.status == http.StatusUnprocessableEntity && jsonPatch != nil {
+			// Point validation errors at the patch operations which caused them
+			// rather than at the PUT request body.
+			remapErrors(api, putCtx, jsonPatch)
+		}
+		putCtx.writeTo(ctx)
+	})))
+}
+
+// subContext wraps the incoming `PATCH` request's context in order to call the
+// `GET` or `PUT` operation handlers in-process. It overrides the operation,
+// method, request headers & body, and captures the response.
+type subContext struct {
+	huma.ContextWrapper
+	op      *huma.Operation
+	method  string
+	headers http.Header
+	body    []byte
+
+	status      int
+	respHeaders http.Header
+	respBody    bytes.Buffer
+}
+
+// newSubContext creates a new sub-request context. Request headers are
+// copied from the incoming request except for those describing the content
+// and any conditional request headers.
+func newSubContext(ctx huma.Context, op *huma.Operation, method string, body []byte) *subContext {
+	headers := http.Header{}
+	ctx.EachHeader(func(k, v string) {
+		switch http.CanonicalHeaderKey(k) {
+		case "Accept", "Accept-Encoding", "Content-Type", "Content-Length", "Content-Encoding":
+			return
+		}
+		if isConditional(k) {
+			return
+		}
+		headers.Add(k, v)
+	})
+	return &subContext{
+		ContextWrapper: huma.WrapContext(ctx),
+		op:             op,
+		method:         method,
+		headers:        headers,
+		body:           body,
+		status:         http.StatusOK,
+		respHeaders:    http.Header{},
+	}
+}
+
+func (c *subContext) Operation() *huma.Operation {
+	return c.op
+}
+
+func (c *subContext) Method() string {
+	return c.method
+}
+
+func (c *subContext) Header(name string) string {
+	return c.headers.Get(name)
+}
+
+func (c *subContext) EachHeader(cb func(name, value string)) {
+	for name, values := range c.headers {
+		for _, value := range values {
+			cb(name, value)
 		}
+	}
+}
+
+func (c *subContext) BodyReader() io.Reader {
+	return bytes.NewReader(c.body)
+}
+
+func (c *subContext) SetReadDeadline(deadline time.Time) error {
+	// The body is already in memory.
+	return nil
+}
+
+func (c *subContext) SetStatus(code int) {
+	c.status = code
+}
+
+func (c *subContext) SetHeader(name, value string) {
+	c.respHeaders.Set(name, value)
+}
+
+func (c *subContext) AppendHeader(name, value string) {
+	c.respHeaders.Add(name, value)
+}
 
-		putWriter := httptest.NewRecorder()
-		adapter.ServeHTTP(putWriter, putReq)
-		for key, values := range putWriter.Header() {
-			for _, value := range values {
-				ctx.SetHeader(key, value)
+func (c *subContext) BodyWriter() io.Writer {
+	return &c.respBody
+}
+
+// writeTo writes the captured response to the given context.
+func (c *subContext) writeTo(ctx huma.Context) {
+	for key, values := range c.respHeaders {
+		for _, value := range values {
+			ctx.AppendHeader(key, value)
+		}
+	}
+	ctx.SetStatus(c.status)
+	ctx.BodyWriter().Write(c.respBody.Bytes())
+}
+
+// isConditional returns true if the header is a conditional request header.
+func isConditional(name string) bool {
+	switch http.CanonicalHeaderKey(name) {
+	case "If-Match", "If-None-Match", "If-Modified-Since", "If-Unmodified-Since":
+		return true
+	}
+	return false
+}
+
+// trimETag removes the quotes and `W/` prefix from an ETag value.
+func trimETag(value string) string {
+	return strings.Trim(strings.TrimPrefix(value, "W/"), "\"")
+}
+
+// preconditions parses the conditional request headers sent by the client.
+func preconditions(ctx huma.Context) *conditional.Params {
+	p := &conditional.Params{}
+	ctx.EachHeader(func(k, v string) {
+		switch http.CanonicalHeaderKey(k) {
+		case "If-Match":
+			for _, tag := range strings.Split(v, ",") {
+				p.IfMatch = append(p.IfMatch, strings.TrimSpace(tag))
+			}
+		case "If-None-Match":
+			for _, tag := range strings.Split(v, ",") {
+				p.IfNoneMatch = append(p.IfNoneMatch, strings.TrimSpace(tag))
 			}
+		case "If-Modified-Since":
+			p.IfModifiedSince, _ = http.ParseTime(v)
+		case "If-Unmodified-Since":
+			p.IfUnmodifiedSince, _ = http.ParseTime(v)
 		}
-		ctx.SetStatus(putWriter.Code)
-		io.Copy(ctx.BodyWriter(), putWriter.Body)
 	})
+	p.Resolve(ctx)
+	return p
+}
+
+// writeErr writes a status error to the client.
+func writeErr(api huma.API, ctx huma.Context, err huma.StatusError) {
+	ct, _ := api.Negotiate(ctx.Header("Accept"))
+	if ctf, ok := err.(huma.ContentTypeFilter); ok {
+		ct = ctf.ContentType(ct)
+	}
+	ctx.SetHeader("Content-Type", ct)
+	ctx.SetStatus(err.GetStatus())
+	api.Marshal(ctx, strconv.Itoa(err.GetStatus()), ct, err)
 }
@@ -0,0 +1,460 @@
+package autopatch
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/conditional"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type Thing struct {
+	ID    string   `json:"id"`
+	Price int      `json:"price" minimum:"0"`
+	Tags  []string `json:"tags,omitempty" maxItems:"1"`
+}
+
+type ThingResponse struct {
+	ETag string `header:"ETag"`
+	Body Thing
+}
+
+func TestPatchConditional(t *testing.T) {
+	_, api := humatest.New(t)
+
+	db := map[string]*Thing{
+		"test": {ID: "test", Price: 100},
+	}
+	version := 1
+	race := false
+	etag := func() string {
+		return `"v` + string(rune('0'+version)) + `"`
+	}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-thing",
+		Method:      http.MethodGet,
+		Path:        "/things/{id}",
+	}, func(ctx context.Context, input *struct {
+		ID string `path:"id"`
+	}) (*ThingResponse, error) {
+		thing := db[input.ID]
+		if thing == nil {
+			return nil, huma.Error404NotFound("not found")
+		}
+		resp := &ThingResponse{ETag: etag(), Body: *thing}
+		if race {
+			// Simulate another writer modifying the resource after our read.
+			version++
+		}
+		return resp, nil
+	})
+
+	puts := 0
+	huma.Register(api, huma.Operation{
+		OperationID: "put-thing",
+		Method:      http.MethodPut,
+		Path:        "/things/{id}",
+	}, func(ctx context.Context, input *struct {
+		conditional.Params
+		ID   string `path:"id"`
+		Body Thing
+	}) (*ThingResponse, error) {
+		if err := input.PreconditionFailed(strings.Trim(etag(), `"`), time.Time{}); err != nil {
+			return nil, err
+		}
+		puts++
+		version++
+		db[input.ID] = &input.Body
+		return &ThingResponse{ETag: etag(), Body: input.Body}, nil
+	})
+
+	AutoPatch(api)
+
+	// Documented in the OpenAPI.
+	assert.Contains(t, api.OpenAPI().Paths["/things/{id}"].Patch.Responses, "412")
+
+	// Unconditional patch uses the GET's ETag for the PUT.
+	resp := api.Patch("/things/test", strings.NewReader(`{"price": 200}`))
+	assert.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Equal(t, `"v2"`, resp.Header().Get("ETag"))
+	assert.Equal(t, 200, db["test"].Price)
+
+	// Stale client precondition fails before anything is written.
+	resp = api.Patch("/things/test", "If-Match: \"v1\"", strings.NewReader(`{"price": 300}`))
+	assert.Equal(t, http.StatusPreconditionFailed, resp.Code, resp.Body.String())
+	assert.Equal(t, 1, puts)
+	assert.Equal(t, 200, db["test"].Price)
+
+	resp = api.Patch("/things/test", "If-None-Match: *", strings.NewReader(`{"price": 300}`))
+	assert.Equal(t, http.StatusPreconditionFailed, resp.Code, resp.Body.String())
+
+	// Current precondition succeeds.
+	resp = api.Patch("/things/test", "If-Match: \"v2\"", strings.NewReader(`{"price": 300}`))
+	assert.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Equal(t, 300, db["test"].Price)
+
+	// A concurrent write between the GET & PUT is detected.
+	race = true
+	resp = api.Patch("/things/test", strings.NewReader(`{"price": 400}`))
+	race = false
+	assert.Equal(t, http.StatusPreconditionFailed, resp.Code, resp.Body.String())
+	assert.Equal(t, 300, db["test"].Price)
+
+	// No change returns the current ETag.
+	resp = api.Patch("/things/test", strings.NewReader(`{"price": 300}`))
+	assert.Equal(t, http.StatusNotModified, resp.Code)
+	assert.Equal(t, `"v4"`, resp.Header().Get("ETag"))
+
+	// Errors from the GET are passed through.
+	resp = api.Patch("/things/missing", strings.NewReader(`{"price": 300}`))
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+}
+
+func TestPatchPreconditionRequired(t *testing.T) {
+	_, api := humatest.New(t)
+
+	thing := Thing{ID: "test", Price: 100}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-thing",
+		Method:      http.MethodGet,
+		Path:        "/things/{id}",
+	}, func(ctx context.Context, input *struct {
+		ID string `path:"id"`
+	}) (*ThingResponse, error) {
+		return &ThingResponse{ETag: `"v1"`, Body: thing}, nil
+	})
+
+	huma.Register(api, conditional.Require(huma.Operation{
+		OperationID: "put-thing",
+		Method:      http.MethodPut,
+		Path:        "/things/{id}",
+	}), func(ctx context.Context, input *struct {
+		conditional.Params
+		ID   string `path:"id"`
+		Body Thing
+	}) (*ThingResponse, error) {
+		err := conditional.Update(ctx, &input.Params,
+			func(ctx context.Context) (*Thing, string, time.Time, error) {
+				return &thing, "v1", time.Time{}, nil
+			},
+			func(ctx context.Context, _ *Thing) error {
+				thing = input.Body
+				return nil
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+		return &ThingResponse{ETag: `"v2"`, Body: thing}, nil
+	})
+
+	AutoPatch(api)
+
+	assert.Contains(t, api.OpenAPI().Paths["/things/{id}"].Patch.Responses, "428")
+
+	// The PUT requires a precondition, so a blind PATCH must be rejected.
+	resp := api.Patch("/things/test", strings.NewReader(`{"price": 200}`))
+	assert.Equal(t, http.StatusPreconditionRequired, resp.Code, resp.Body.String())
+	assert.Equal(t, 100, thing.Price)
+
+	resp = api.Patch("/things/test", "If-Match: \"v1\"", strings.NewReader(`{"price": 200}`))
+	assert.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Equal(t, 200, thing.Price)
+}
+
+type ctxKey struct{}
+
+func TestPatchInProcess(t *testing.T) {
+	router, api := humatest.New(t)
+
+	// Router middleware should only run once for the PATCH request, and any
+	// context values it sets must be visible to the GET & PUT handlers.
+	routerCalls := 0
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			routerCalls++
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKey{}, "user1")))
+		})
+	})
+
+	// Huma middleware also runs once.
+	humaCalls := 0
+	api.UseMiddleware(func(ctx huma.Context, next func(huma.Context)) {
+		humaCalls++
+		next(ctx)
+	})
+
+	thing := Thing{ID: "test", Price: 100}
+	users := []string{}
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-thing",
+		Method:      http.MethodGet,
+		Path:        "/things/{id}",
+	}, func(ctx context.Context, input *struct {
+		ID string `path:"id"`
+	}) (*ThingResponse, error) {
+		users = append(users, ctx.Value(ctxKey{}).(string))
+		return &ThingResponse{Body: thing}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "put-thing",
+		Method:      http.MethodPut,
+		Path:        "/things/{id}",
+	}, func(ctx context.Context, input *struct {
+		ID   string `path:"id"`
+		Body Thing
+	}) (*ThingResponse, error) {
+		users = append(users, ctx.Value(ctxKey{}).(string))
+		thing.Price = input.Body.Price
+		return &ThingResponse{Body: thing}, nil
+	})
+
+	AutoPatch(api)
+
+	resp := api.Patch("/things/test", strings.NewReader(`{"price": 200}`))
+	assert.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Equal(t, 200, thing.Price)
+	assert.Equal(t, 1, routerCalls)
+	assert.Equal(t, 1, humaCalls)
+	assert.Equal(t, []string{"user1", "user1"}, users)
+
+	// The PUT's validation still runs.
+	resp = api.Patch("/things/test", strings.NewReader(`{"price": -1}`))
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code, resp.Body.String())
+	assert.Equal(t, 200, thing.Price)
+}
+
+func registerThings(api huma.API, thing *Thing) {
+	huma.Register(api, huma.Operation{
+		OperationID: "get-thing",
+		Method:      http.MethodGet,
+		Path:        "/things/{id}",
+	}, func(ctx context.Context, input *struct {
+		ID string `path:"id"`
+	}) (*ThingResponse, error) {
+		return &ThingResponse{Body: *thing}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "put-thing",
+		Method:      http.MethodPut,
+		Path:        "/things/{id}",
+	}, func(ctx context.Context, input *struct {
+		ID   string `path:"id"`
+		Body Thing
+	}) (*ThingResponse, error) {
+		thing.Price = input.Body.Price
+		thing.Tags = input.Body.Tags
+		return &ThingResponse{Body: *thing}, nil
+	})
+
+	AutoPatch(api)
+}
+
+func TestPatchErrors(t *testing.T) {
+	// The default config marshals `application/problem+json` error bodies.
+	_, api := humatest.New(t, huma.DefaultConfig("Test API", "1.0.0"))
+	thing := &Thing{ID: "test", Price: 100}
+	registerThings(api, thing)
+
+	jsonPatch := "Content-Type: application/json-patch+json"
+
+	// Failed test operations are a conflict.
+	resp := api.Patch("/things/test", jsonPatch, strings.NewReader(`[
+		{"op": "test", "path": "/price", "value": 100},
+		{"op": "test", "path": "/price", "value": 50},
+		{"op": "replace", "path": "/price", "value": 200}
+	]`))
+	assert.Equal(t, http.StatusConflict, resp.Code, resp.Body.String())
+	assert.Contains(t, resp.Body.String(), `"location":"body[1]"`)
+	assert.Equal(t, 100, thing.Price)
+
+	// Other failures point at the failed operation.
+	resp = api.Patch("/things/test", jsonPatch, strings.NewReader(`[
+		{"op": "remove", "path": "/missing"}
+	]`))
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code, resp.Body.String())
+	assert.Contains(t, resp.Body.String(), `"location":"body[0]"`)
+
+	// Validation errors are mapped back to the patch operations.
+	resp = api.Patch("/things/test", jsonPatch, strings.NewReader(`[
+		{"op": "add", "path": "/tags", "value": ["a", "long"]},
+		{"op": "replace", "path": "/price", "value": -1}
+	]`))
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code, resp.Body.String())
+	assert.Contains(t, resp.Body.String(), `"location":"body[1].value"`)
+	assert.Contains(t, resp.Body.String(), `"location":"body[0].value"`)
+
+	// Merge patch errors already mirror the resource.
+	resp = api.Patch("/things/test", strings.NewReader(`{"price": -1}`))
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code, resp.Body.String())
+	assert.Contains(t, resp.Body.String(), `"location":"body.price"`)
+
+	// Unsupported patch formats are rejected.
+	resp = api.Patch("/things/test", "Content-Type: text/plain", strings.NewReader(`price=200`))
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.Code, resp.Body.String())
+	assert.Equal(t, 100, thing.Price)
+}
+
+func TestPatchCBOR(t *testing.T) {
+	r := chi.NewRouter()
+	config := huma.DefaultConfig("Test API", "1.0.0")
+	config.Formats = map[string]huma.Format{
+		"application/cbor": huma.DefaultCBORFormat,
+		"cbor":             huma.DefaultCBORFormat,
+	}
+	config.DefaultFormat = "application/cbor"
+	api := humatest.NewTestAPI(t, r, config)
+	thing := &Thing{ID: "test", Price: 100}
+	registerThings(api, thing)
+
+	patch, _ := cbor.Marshal(map[string]any{"price": 200})
+	resp := api.Patch("/things/test", "Content-Type: application/merge-patch+cbor", bytes.NewReader(patch))
+	assert.Equal(t, http.StatusOK, resp.Code, resp.Body.String())
+	assert.Equal(t, "application/cbor", resp.Header().Get("Content-Type"))
+	assert.Equal(t, 200, thing.Price)
+
+	var body map[string]any
+	assert.NoError(t, cbor.Unmarshal(resp.Body.Bytes(), &body))
+	assert.EqualValues(t, 200, body["price"])
+}
+
+func TestPatchLocation(t *testing.T) {
+	patch, err := jsonpatch.DecodePatch([]byte(`[
+		{"op": "add", "path": "/items/0", "value": {"name": "a"}},
+		{"op": "remove", "path": "/tags"},
+		{"op": "replace", "path": "/price", "value": 1}
+	]`))
+	assert.NoError(t, err)
+
+	for _, item := range []struct {
+		location string
+		expected string
+	}{
+		{"body.items[0].name", "body[0].value.name"},
+		{"body.items[0]", "body[0].value"},
+		{"body.items", "body[0]"},
+		{"body.tags", "body[1]"},
+		{"body.price", "body[2].value"},
+		{"body.other", ""},
+	} {
+		ptr, ok := locationToPointer(item.location)
+		assert.True(t, ok)
+		assert.Equal(t, item.expected, patchLocation(patch, ptr), item.location)
+	}
+
+	_, ok := locationToPointer("query.foo")
+	assert.False(t, ok)
+}
+
+func TestPatchCompatibility(t *testing.T) {
+	logs := &bytes.Buffer{}
+	config := huma.DefaultConfig("Test API", "1.0.0")
+	config.Logger = slog.New(slog.NewTextHandler(logs, nil))
+	_, api := humatest.New(t, config)
+
+	type Other struct {
+		Name string `json:"name"`
+	}
+
+	type Partial struct {
+		ID    string `json:"id"`
+		Price int    `json:"price"`
+	}
+
+	register := func(path string, putBody any, getExt, putExt map[string]any) {
+		huma.Register(api, huma.Operation{
+			Method:     http.MethodGet,
+			Path:       path,
+			Extensions: getExt,
+		}, func(ctx context.Context, input *struct{}) (*ThingResponse, error) {
+			return &ThingResponse{}, nil
+		})
+
+		switch putBody.(type) {
+		case Thing:
+			huma.Register(api, huma.Operation{
+				Method:     http.MethodPut,
+				Path:       path,
+				Extensions: putExt,
+			}, func(ctx context.Context, input *struct{ Body Thing }) (*struct{}, error) {
+				return nil, nil
+			})
+		case Other:
+			huma.Register(api, huma.Operation{
+				Method:     http.MethodPut,
+				Path:       path,
+				Extensions: putExt,
+			}, func(ctx context.Context, input *struct{ Body Other }) (*struct{}, error) {
+				return nil, nil
+			})
+		case Partial:
+			huma.Register(api, huma.Operation{
+				Method:     http.MethodPut,
+				Path:       path,
+				Extensions: putExt,
+			}, func(ctx context.Context, input *struct{ Body Partial }) (*struct{}, error) {
+				return nil, nil
+			})
+		}
+	}
+
+	register("/same", Thing{}, nil, nil)
+	register("/other", Other{}, nil, nil)
+	register("/partial", Partial{}, nil, nil)
+	register("/opt-out", Thing{}, nil, map[string]any{ExtensionKey: false})
+	register("/opt-in", Other{}, map[string]any{ExtensionKey: true}, nil)
+
+	AutoPatch(api)
+
+	paths := api.OpenAPI().Paths
+	assert.NotNil(t, paths["/same"].Patch)
+	assert.Nil(t, paths["/other"].Patch)
+	assert.Nil(t, paths["/partial"].Patch)
+	assert.Nil(t, paths["/opt-out"].Patch)
+	assert.NotNil(t, paths["/opt-in"].Patch)
+
+	assert.Contains(t, logs.String(), "level=WARN msg=\"autopatch skipping incompatible resource\" path=/other")
+	assert.Contains(t, logs.String(), "path=/partial")
+	assert.NotContains(t, logs.String(), "path=/opt-out")
+}
+
+func TestPatchCompatibilityResponseChoice(t *testing.T) {
+	registry := huma.NewMapRegistry("#/components/schemas/", huma.DefaultSchemaNamer)
+	schema := registry.Schema(reflect.TypeOf(Thing{}), true, "Thing")
+	content := map[string]*huma.MediaType{"application/json": {Schema: schema}}
+
+	get := &huma.Operation{Responses: map[string]*huma.Response{
+		"202": {Description: "Accepted"},
+		"204": {Description: "No Content"},
+		"200": {Content: content},
+	}}
+	put := &huma.Operation{RequestBody: &huma.RequestBody{Content: content}}
+
+	// The 200 response is always used regardless of map iteration order.
+	for i := 0; i < 20; i++ {
+		assert.NoError(t, compatible(registry, get, put))
+	}
+
+	// Without a 200, the lowest 2xx response is used.
+	delete(get.Responses, "200")
+	get.Responses["201"] = &huma.Response{Content: content}
+	for i := 0; i < 20; i++ {
+		assert.NoError(t, compatible(registry, get, put))
+	}
+}
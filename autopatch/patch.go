@@ -0,0 +1,239 @@
+package autopatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+// isJSON returns true if the content type is JSON or uses the `+json` suffix.
+func isJSON(contentType string) bool {
+	ct := strings.TrimSpace(strings.Split(contentType, ";")[0])
+	return ct == "" || ct == "application/json" || strings.HasSuffix(ct, "+json")
+}
+
+// normalize converts decoded values into types which can be marshaled as
+// JSON, e.g. the `map[any]any` produced by some CBOR decoders.
+func normalize(v any) any {
+	switch t := v.(type) {
+	case map[any]any:
+		m := make(map[string]any, len(t))
+		for k, value := range t {
+			m[fmt.Sprintf("%v", k)] = normalize(value)
+		}
+		return m
+	case map[string]any:
+		for k, value := range t {
+			t[k] = normalize(value)
+		}
+	case []any:
+		for i, value := range t {
+			t[i] = normalize(value)
+		}
+	}
+	return v
+}
+
+// convertNumbers replaces decoded `json.Number` values with integers where
+// possible, falling back to floats.
+func convertNumbers(v any) any {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		f, _ := t.Float64()
+		return f
+	case map[string]any:
+		for k, value := range t {
+			t[k] = convertNumbers(value)
+		}
+	case []any:
+		for i, value := range t {
+			t[i] = convertNumbers(value)
+		}
+	}
+	return v
+}
+
+// toJSON converts data in any of the API's configured formats into JSON.
+func toJSON(api huma.API, contentType string, data []byte) ([]byte, error) {
+	if isJSON(contentType) {
+		return data, nil
+	}
+	var tmp any
+	if err := api.Unmarshal(contentType, data, &tmp); err != nil {
+		return nil, err
+	}
+	return json.Marshal(normalize(tmp))
+}
+
+// fromJSON converts JSON data into the given content type using the API's
+// configured formats. The context is used only to capture the output.
+func fromJSON(api huma.API, ctx *subContext, contentType string, data []byte) ([]byte, error) {
+	if isJSON(contentType) {
+		return data, nil
+	}
+	// Use numbers so integers are not encoded as floats in the new format.
+	var tmp any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&tmp); err != nil {
+		return nil, err
+	}
+	if err := api.Marshal(ctx, "", contentType, convertNumbers(tmp)); err != nil {
+		return nil, err
+	}
+	return ctx.respBody.Bytes(), nil
+}
+
+// applyJSONPatch applies an RFC 6902 JSON Patch one operation at a time so
+// that failures can be reported for the specific operation. A failed `test`
+// operation results in a `409 Conflict`.
+func applyJSONPatch(doc, data []byte) ([]byte, jsonpatch.Patch, huma.StatusError) {
+	patch, err := jsonpatch.DecodePatch(data)
+	if err != nil {
+		return nil, nil, huma.NewError(http.StatusUnprocessableEntity, "Unable to decode JSON Patch", err)
+	}
+
+	for i, op := range patch {
+		doc, err = jsonpatch.Patch{op}.Apply(doc)
+		if err != nil {
+			status := http.StatusUnprocessableEntity
+			msg := "Unable to apply patch"
+			if errors.Is(err, jsonpatch.ErrTestFailed) {
+				status = http.StatusConflict
+				msg = "Patch test operation failed"
+			}
+			path, _ := op.Path()
+			return nil, nil, huma.NewError(status, msg, &huma.ErrorDetail{
+				Message:  err.Error(),
+				Location: "body[" + strconv.Itoa(i) + "]",
+				Value:    path,
+			})
+		}
+	}
+
+	return doc, patch, nil
+}
+
+// locationToPointer converts an error location like `body.items[0].name` into
+// an RFC 6901 JSON Pointer like `/items/0/name`. Returns false if the location
+// is not within the body.
+func locationToPointer(location string) (string, bool) {
+	if !strings.HasPrefix(location, "body") {
+		return "", false
+	}
+	location = location[len("body"):]
+	var ptr strings.Builder
+	for location != "" {
+		var part string
+		switch location[0] {
+		case '.':
+			location = location[1:]
+			end := strings.IndexAny(location, ".[")
+			if end == -1 {
+				end = len(location)
+			}
+			part, location = location[:end], location[end:]
+		case '[':
+			end := strings.IndexByte(location, ']')
+			if end == -1 {
+				return "", false
+			}
+			part, location = location[1:end], location[end+1:]
+		default:
+			return "", false
+		}
+		ptr.WriteByte('/')
+		ptr.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(part))
+	}
+	return ptr.String(), true
+}
+
+// pointerToLocation converts a relative JSON Pointer like `/items/0` into an
+// error location suffix like `.items[0]`.
+func pointerToLocation(ptr string) string {
+	var loc strings.Builder
+	for _, part := range strings.Split(strings.TrimPrefix(ptr, "/"), "/") {
+		if part == "" {
+			continue
+		}
+		part = strings.NewReplacer("~1", "/", "~0", "~").Replace(part)
+		if _, err := strconv.Atoi(part); err == nil {
+			loc.WriteString("[" + part + "]")
+		} else {
+			loc.WriteString("." + part)
+		}
+	}
+	return loc.String()
+}
+
+// patchLocation returns the location within a JSON Patch document of the
+// operation responsible for the given resource pointer, or an empty string
+// if no operation modified it. The last matching operation wins.
+func patchLocation(patch jsonpatch.Patch, ptr string) string {
+	for i := len(patch) - 1; i >= 0; i-- {
+		op := patch[i]
+		path, err := op.Path()
+		if err != nil {
+			continue
+		}
+		prefix := "body[" + strconv.Itoa(i) + "]"
+		hasValue := op.Kind() == "add" || op.Kind() == "replace"
+		switch {
+		case ptr == path && hasValue:
+			return prefix + ".value"
+		case strings.HasPrefix(ptr, path+"/") && hasValue:
+			return prefix + ".value" + pointerToLocation(ptr[len(path):])
+		case ptr == path, strings.HasPrefix(ptr, path+"/"), strings.HasPrefix(path, ptr+"/"):
+			return prefix
+		}
+	}
+	return ""
+}
+
+// remapErrors rewrites the locations of validation errors in a captured PUT
+// response so they point at the JSON Patch operations which caused them.
+func remapErrors(api huma.API, ctx *subContext, patch jsonpatch.Patch) {
+	ct := ctx.respHeaders.Get("Content-Type")
+	var model any
+	if err := api.Unmarshal(ct, ctx.respBody.Bytes(), &model); err != nil {
+		return
+	}
+	m, ok := normalize(model).(map[string]any)
+	if !ok {
+		return
+	}
+	details, _ := m["errors"].([]any)
+	changed := false
+	for _, d := range details {
+		detail, ok := d.(map[string]any)
+		if !ok {
+			continue
+		}
+		location, _ := detail["location"].(string)
+		ptr, ok := locationToPointer(location)
+		if !ok {
+			continue
+		}
+		if loc := patchLocation(patch, ptr); loc != "" {
+			detail["location"] = loc
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+
+	// Re-encode the modified error in the same format.
+	ctx.respBody.Reset()
+	api.Marshal(ctx, strconv.Itoa(ctx.status), ct, m)
+}
@@ -0,0 +1,405 @@
+// Package compress provides opt-in response compression and request body
+// decompression for Huma operations. Responses are compressed using the best
+// content coding negotiated via the `Accept-Encoding` request header, while
+// request bodies sent with a `Content-Encoding` header are transparently
+// decompressed before being read by the operation.
+//
+//	api := humachi.New(router, huma.DefaultConfig("My API", "1.0.0"))
+//	api.UseMiddleware(compress.New(api, compress.Options{}))
+package compress
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/negotiation"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Encoder is a streaming compressor for a single content coding. It is
+// implemented by the gzip, brotli, and zstd writers.
+type Encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// Encoders provides constructors for each supported content coding by name.
+// You may add or replace entries at service startup to customize compression
+// levels or support additional codings.
+var Encoders = map[string]func() Encoder{
+	"br": func() Encoder {
+		return brotli.NewWriterLevel(nil, 4)
+	},
+	"gzip": func() Encoder {
+		w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return w
+	},
+	"zstd": func() Encoder {
+		w, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+		return w
+	},
+}
+
+// Decoders provides constructors for request body decompression by content
+// coding name. You may add or replace entries at service startup.
+var Decoders = map[string]func(r io.Reader) (io.ReadCloser, error){
+	"br": func(r io.Reader) (io.ReadCloser, error) {
+		return io.NopCloser(brotli.NewReader(r)), nil
+	},
+	"gzip": func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+	"zstd": func(r io.Reader) (io.ReadCloser, error) {
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	},
+}
+
+// DefaultContentTypes is the default allow list of response content types
+// which will be compressed. Entries ending in `/` match any subtype, while
+// entries starting with `+` match a structured syntax suffix.
+var DefaultContentTypes = []string{
+	"application/json",
+	"application/cbor",
+	"application/yaml",
+	"application/xml",
+	"text/",
+	"+json",
+	"+cbor",
+	"+yaml",
+	"+xml",
+}
+
+// Options configures the compression middleware.
+type Options struct {
+	// Encodings lists the content codings to use for responses in server
+	// preference order. Defaults to `br`, `zstd`, `gzip`. Each entry must
+	// exist in `compress.Encoders`.
+	Encodings []string
+
+	// MinSize is the minimum response body size in bytes before compression
+	// is used. Smaller responses are sent uncompressed since the overhead is
+	// not worth it. Defaults to 1024. Streaming responses are compressed as
+	// soon as they are flushed regardless of size.
+	MinSize int
+
+	// ContentTypes is the allow list of response content types to compress.
+	// Defaults to `compress.DefaultContentTypes`.
+	ContentTypes []string
+
+	// DisableRequestDecompression turns off decompression of request bodies
+	// sent with a `Content-Encoding` header.
+	DisableRequestDecompression bool
+}
+
+var bufPool = sync.Pool{
+	New: func() any {
+		return bytes.NewBuffer(make([]byte, 0, 1024))
+	},
+}
+
+// New creates a new compression middleware for the given API. Request bodies
+// are decompressed *before* the operation's `MaxBodyBytes` limit is applied,
+// so the limit applies to the decompressed size.
+func New(api huma.API, opts Options) func(ctx huma.Context, next func(huma.Context)) {
+	if len(opts.Encodings) == 0 {
+		opts.Encodings = []string{"br", "zstd", "gzip"}
+	}
+	if opts.MinSize == 0 {
+		opts.MinSize = 1024
+	}
+	if opts.ContentTypes == nil {
+		opts.ContentTypes = DefaultContentTypes
+	}
+
+	pools := make(map[string]*sync.Pool, len(opts.Encodings))
+	for _, name := range opts.Encodings {
+		constructor := Encoders[name]
+		if constructor == nil {
+			panic("unknown compression encoding " + name)
+		}
+		pools[name] = &sync.Pool{
+			New: func() any {
+				return constructor()
+			},
+		}
+	}
+
+	supported := make([]string, 0, len(Decoders))
+	for name := range Decoders {
+		supported = append(supported, name)
+	}
+	// Sort for a stable header value since map iteration order is random.
+	sort.Strings(supported)
+	acceptEncoding := strings.Join(supported, ", ")
+
+	return func(ctx huma.Context, next func(huma.Context)) {
+		cctx := &compressContext{ContextWrapper: huma.WrapContext(ctx)}
+
+		if ce := ctx.Header("Content-Encoding"); ce != "" && ce != "identity" && !opts.DisableRequestDecompression {
+			decoder := Decoders[strings.ToLower(ce)]
+			if decoder == nil {
+				ctx.SetHeader("Accept-Encoding", acceptEncoding)
+				huma.WriteErr(api, ctx, http.StatusUnsupportedMediaType, "unsupported content encoding "+ce)
+				return
+			}
+			body := ctx.BodyReader()
+			if body == nil {
+				body = bytes.NewReader(nil)
+			}
+			reader, err := decoder(body)
+			if err != nil {
+				huma.WriteErr(api, ctx, http.StatusBadRequest, "unable to decode request body", err)
+				return
+			}
+			defer reader.Close()
+			cctx.body = reader
+		}
+
+		// The response may differ based on the client's accepted encodings, so
+		// caches must take this into account.
+		ctx.AppendHeader("Vary", "Accept-Encoding")
+
+		encoding := negotiation.SelectEncoding(ctx.Header("Accept-Encoding"), opts.Encodings)
+		if encoding == "identity" || encoding == "" || ctx.Method() == http.MethodHead {
+			next(cctx)
+			return
+		}
+
+		buf := bufPool.Get().(*bytes.Buffer)
+		w := &writer{
+			ctx:           ctx,
+			WriterWrapper: huma.WriterWrapper{Writer: ctx.BodyWriter()},
+			opts:          &opts,
+			pool:          pools[encoding],
+			encoding:      encoding,
+			buf:           buf,
+		}
+		cctx.w = w
+
+		next(cctx)
+
+		w.close()
+		buf.Reset()
+		bufPool.Put(buf)
+	}
+}
+
+// compressContext wraps a `huma.Context` to decompress the request body and
+// compress the response body.
+type compressContext struct {
+	huma.ContextWrapper
+	body io.Reader
+	w    *writer
+}
+
+func (c *compressContext) BodyReader() io.Reader {
+	if c.body != nil {
+		return c.body
+	}
+	return c.Unwrap().BodyReader()
+}
+
+func (c *compressContext) BodyWriter() io.Writer {
+	if c.w != nil {
+		return c.w
+	}
+	return c.Unwrap().BodyWriter()
+}
+
+func (c *compressContext) SetStatus(code int) {
+	if c.w != nil && !c.w.decided {
+		// Delay writing the status until we know whether the response will be
+		// compressed, since that requires setting headers.
+		c.w.status = code
+		return
+	}
+	c.Unwrap().SetStatus(code)
+}
+
+func (c *compressContext) SetHeader(name, value string) {
+	if c.track(name, value) {
+		c.Unwrap().SetHeader(name, value)
+	}
+}
+
+func (c *compressContext) AppendHeader(name, value string) {
+	if c.track(name, value) {
+		c.Unwrap().AppendHeader(name, value)
+	}
+}
+
+// track records response headers that impact whether compression is used,
+// since the context provides no way to read response headers back. It
+// returns false if the header should not be written yet.
+func (c *compressContext) track(name, value string) bool {
+	if c.w == nil {
+		return true
+	}
+	switch {
+	case strings.EqualFold(name, "Content-Type"):
+		c.w.contentType = value
+	case strings.EqualFold(name, "Content-Encoding"):
+		c.w.contentEncoding = value
+	case strings.EqualFold(name, "Content-Length"):
+		// The length is wrong once compressed, so hold it until we know.
+		if !c.w.decided {
+			c.w.contentLength = value
+			return false
+		}
+		return c.w.enc == nil
+	}
+	return true
+}
+
+// writer buffers the start of the response body until it knows whether the
+// response should be compressed, then writes through the encoder if needed.
+// Write deadlines are passed through to the underlying writer.
+type writer struct {
+	huma.WriterWrapper
+	ctx      huma.Context
+	opts     *Options
+	pool     *sync.Pool
+	encoding string
+
+	status          int
+	contentType     string
+	contentEncoding string
+	contentLength   string
+
+	buf     *bytes.Buffer
+	decided bool
+	enc     Encoder
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if !w.decided {
+		w.buf.Write(p)
+		if w.buf.Len() < w.opts.MinSize {
+			return len(p), nil
+		}
+		w.decide(true)
+		return len(p), w.writeBuffered()
+	}
+
+	if w.enc != nil {
+		return w.enc.Write(p)
+	}
+	return w.Writer.Write(p)
+}
+
+// Flush writes any buffered data through to the client. Streaming responses
+// like SSE flush after each message, so compression is decided on the first
+// flush regardless of the minimum size.
+func (w *writer) Flush() {
+	if !w.decided {
+		w.decide(true)
+		w.writeBuffered()
+	}
+	if w.enc != nil {
+		w.enc.Flush()
+	}
+	w.WriterWrapper.Flush()
+}
+
+// Hijack passes through connection hijacking to the underlying writer, if
+// supported. This is used by e.g. the `websocket` package. Nothing further is
+// written to the response once the connection has been hijacked.
+func (w *writer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if _, ok := w.Writer.(http.Hijacker); ok {
+		w.decided = true
+	}
+	return w.WriterWrapper.Hijack()
+}
+
+func (w *writer) compressible() bool {
+	switch {
+	case w.status == http.StatusNoContent, w.status == http.StatusNotModified, w.status > 0 && w.status < 200:
+		return false
+	case w.contentEncoding != "":
+		// Already encoded, e.g. a pre-compressed file.
+		return false
+	}
+
+	ct := w.contentType
+	if i := strings.IndexByte(ct, ';'); i != -1 {
+		ct = ct[:i]
+	}
+	ct = strings.TrimSpace(ct)
+	for _, allowed := range w.opts.ContentTypes {
+		switch {
+		case strings.HasSuffix(allowed, "/"):
+			if strings.HasPrefix(ct, allowed) {
+				return true
+			}
+		case strings.HasPrefix(allowed, "+"):
+			if strings.HasSuffix(ct, allowed) {
+				return true
+			}
+		case ct == allowed:
+			return true
+		}
+	}
+	return false
+}
+
+// decide whether to compress, then write the response headers & status.
+func (w *writer) decide(compress bool) {
+	w.decided = true
+	if compress && w.compressible() {
+		w.enc = w.pool.Get().(Encoder)
+		w.enc.Reset(w.Writer)
+		w.ctx.SetHeader("Content-Encoding", w.encoding)
+		// Remove any length set before this middleware, e.g. by the router.
+		if h, ok := w.Writer.(interface{ Header() http.Header }); ok {
+			h.Header().Del("Content-Length")
+		}
+	} else if w.contentLength != "" {
+		w.ctx.SetHeader("Content-Length", w.contentLength)
+	}
+	if w.status != 0 {
+		w.ctx.SetStatus(w.status)
+	}
+}
+
+func (w *writer) writeBuffered() error {
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(w.buf.Bytes())
+	} else {
+		_, err = w.Writer.Write(w.buf.Bytes())
+	}
+	w.buf.Reset()
+	return err
+}
+
+// close finishes the response, writing out anything still buffered.
+func (w *writer) close() {
+	if !w.decided {
+		// The response was too small to be worth compressing.
+		w.decide(false)
+		w.writeBuffered()
+	}
+	if w.enc != nil {
+		w.enc.Close()
+		w.enc.Reset(nil)
+		w.pool.Put(w.enc)
+		w.enc = nil
+	}
+}
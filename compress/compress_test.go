@@ -0,0 +1,222 @@
+package compress
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+type ItemOutput struct {
+	Body struct {
+		Name string `json:"name"`
+	}
+}
+
+func decode(t *testing.T, encoding string, body []byte) string {
+	t.Helper()
+	var r io.Reader
+	var err error
+	switch encoding {
+	case "gzip":
+		r, err = gzip.NewReader(bytes.NewReader(body))
+	case "br":
+		r = brotli.NewReader(bytes.NewReader(body))
+	case "zstd":
+		r, err = zstd.NewReader(bytes.NewReader(body))
+	default:
+		return string(body)
+	}
+	assert.NoError(t, err)
+	decoded, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	return string(decoded)
+}
+
+func TestCompress(t *testing.T) {
+	_, api := humatest.New(t)
+	api.UseMiddleware(New(api, Options{MinSize: 100}))
+
+	big := strings.Repeat("a", 500)
+
+	huma.Register(api, huma.Operation{
+		Method: http.MethodGet,
+		Path:   "/items/{name}",
+	}, func(ctx context.Context, input *struct {
+		Name string `path:"name"`
+	}) (*ItemOutput, error) {
+		resp := &ItemOutput{}
+		resp.Body.Name = input.Name
+		if input.Name == "big" {
+			resp.Body.Name = big
+		}
+		return resp, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		Method: http.MethodGet,
+		Path:   "/image",
+	}, func(ctx context.Context, input *struct{}) (*huma.StreamResponse, error) {
+		return &huma.StreamResponse{
+			Body: func(ctx huma.Context) {
+				ctx.SetHeader("Content-Type", "image/png")
+				ctx.BodyWriter().Write([]byte(big))
+			},
+		}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		Method:        http.MethodPut,
+		Path:          "/items",
+		DefaultStatus: http.StatusNoContent,
+	}, func(ctx context.Context, input *struct {
+		Body struct {
+			Name string `json:"name"`
+		}
+	}) (*struct{}, error) {
+		if input.Body.Name != "compressed" {
+			return nil, huma.Error400BadRequest("wrong name")
+		}
+		return nil, nil
+	})
+
+	for _, encoding := range []string{"br", "zstd", "gzip"} {
+		t.Run(encoding, func(t *testing.T) {
+			resp := api.Get("/items/big", "Accept-Encoding: "+encoding)
+			assert.Equal(t, http.StatusOK, resp.Code)
+			assert.Equal(t, encoding, resp.Header().Get("Content-Encoding"))
+			assert.Equal(t, "Accept-Encoding", resp.Header().Get("Vary"))
+			assert.Contains(t, decode(t, encoding, resp.Body.Bytes()), big)
+		})
+	}
+
+	// Server preference is used on a tie.
+	resp := api.Get("/items/big", "Accept-Encoding: gzip, br")
+	assert.Equal(t, "br", resp.Header().Get("Content-Encoding"))
+
+	// Small responses are not compressed.
+	resp = api.Get("/items/small", "Accept-Encoding: gzip")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Empty(t, resp.Header().Get("Content-Encoding"))
+	assert.Contains(t, resp.Body.String(), `"name":"small"`)
+
+	// No accept encoding, so no compression.
+	resp = api.Get("/items/big")
+	assert.Empty(t, resp.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", resp.Header().Get("Vary"))
+
+	// Content types not in the allow list are not compressed.
+	resp = api.Get("/image", "Accept-Encoding: gzip")
+	assert.Empty(t, resp.Header().Get("Content-Encoding"))
+	assert.Equal(t, big, resp.Body.String())
+
+	// Compressed request bodies are decompressed.
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	gz.Write([]byte(`{"name": "compressed"}`))
+	gz.Close()
+	resp = api.Put("/items", "Content-Encoding: gzip", "Accept-Encoding: gzip", buf)
+	assert.Equal(t, http.StatusNoContent, resp.Code, resp.Body.String())
+	assert.Empty(t, resp.Header().Get("Content-Encoding"))
+
+	// Invalid compressed bodies fail.
+	resp = api.Put("/items", "Content-Encoding: gzip", strings.NewReader(`{"name": "compressed"}`))
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	// Unknown encodings fail.
+	resp = api.Put("/items", "Content-Encoding: foo", strings.NewReader(`{"name": "compressed"}`))
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.Code)
+	assert.Contains(t, resp.Header().Get("Accept-Encoding"), "gzip")
+}
+
+func TestCompressFlush(t *testing.T) {
+	_, api := humatest.New(t)
+	api.UseMiddleware(New(api, Options{}))
+
+	huma.Register(api, huma.Operation{
+		Method: http.MethodGet,
+		Path:   "/stream",
+	}, func(ctx context.Context, input *struct{}) (*huma.StreamResponse, error) {
+		return &huma.StreamResponse{
+			Body: func(ctx huma.Context) {
+				ctx.SetHeader("Content-Type", "text/event-stream")
+				w := ctx.BodyWriter()
+				w.Write([]byte("data: 1\n\n"))
+				w.(http.Flusher).Flush()
+				w.Write([]byte("data: 2\n\n"))
+				w.(http.Flusher).Flush()
+			},
+		}, nil
+	})
+
+	resp := api.Get("/stream", "Accept-Encoding: gzip")
+	assert.Equal(t, "gzip", resp.Header().Get("Content-Encoding"))
+	assert.Equal(t, "data: 1\n\ndata: 2\n\n", decode(t, "gzip", resp.Body.Bytes()))
+}
+
+func TestUnknownEncoding(t *testing.T) {
+	_, api := humatest.New(t)
+	assert.Panics(t, func() {
+		New(api, Options{Encodings: []string{"unknown"}})
+	})
+}
+
+func TestCompressContentLength(t *testing.T) {
+	_, api := humatest.New(t)
+	api.UseMiddleware(New(api, Options{MinSize: 100}))
+
+	huma.Register(api, huma.Operation{
+		Method: http.MethodGet,
+		Path:   "/file/{size}",
+	}, func(ctx context.Context, input *struct {
+		Size int `path:"size"`
+	}) (*huma.StreamResponse, error) {
+		return &huma.StreamResponse{
+			Body: func(ctx huma.Context) {
+				body := strings.Repeat("a", input.Size)
+				ctx.SetHeader("Content-Type", "text/plain")
+				ctx.SetHeader("Content-Length", strconv.Itoa(len(body)))
+				ctx.BodyWriter().Write([]byte(body))
+			},
+		}, nil
+	})
+
+	// The uncompressed length is wrong for a compressed body.
+	resp := api.Get("/file/500", "Accept-Encoding: gzip")
+	assert.Equal(t, "gzip", resp.Header().Get("Content-Encoding"))
+	assert.Empty(t, resp.Header().Get("Content-Length"))
+
+	resp = api.Get("/file/10", "Accept-Encoding: gzip")
+	assert.Empty(t, resp.Header().Get("Content-Encoding"))
+	assert.Equal(t, "10", resp.Header().Get("Content-Length"))
+}
+
+func TestUnsupportedRequestEncoding(t *testing.T) {
+	_, api := humatest.New(t)
+	api.UseMiddleware(New(api, Options{}))
+
+	huma.Register(api, huma.Operation{
+		Method: http.MethodPut,
+		Path:   "/items",
+	}, func(ctx context.Context, input *struct {
+		Body struct{}
+	}) (*struct{}, error) {
+		return nil, nil
+	})
+
+	for i := 0; i < 5; i++ {
+		resp := api.Put("/items", "Content-Encoding: compress", strings.NewReader("{}"))
+		assert.Equal(t, http.StatusUnsupportedMediaType, resp.Code)
+		assert.Equal(t, "br, gzip, zstd", resp.Header().Get("Accept-Encoding"))
+	}
+}
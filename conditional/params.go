@@ -31,12 +31,19 @@ type Params struct {
 	// isWrite tracks whether we should emit errors vs. a 304 Not Modified from
 	// the `PreconditionFailed` method.
 	isWrite bool
+
+	// required tracks whether the operation requires a precondition for writes
+	// as configured via `conditional.Require`.
+	required bool
 }
 
 func (p *Params) Resolve(ctx huma.Context) []error {
 	switch ctx.Method() {
 	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
 		p.isWrite = true
+		if op := ctx.Operation(); op != nil {
+			p.required, _ = op.Metadata[metadataRequired].(bool)
+		}
 	}
 	return nil
 }
@@ -47,6 +54,23 @@ func (p *Params) HasConditionalParams() bool {
 	return len(p.IfMatch) > 0 || len(p.IfNoneMatch) > 0 || !p.IfModifiedSince.IsZero() || !p.IfUnmodifiedSince.IsZero()
 }
 
+// PreconditionRequired returns a `428 Precondition Required` error if the
+// operation was configured with `conditional.Require` but the client sent
+// neither an `If-Match` nor an `If-Unmodified-Since` header.
+func (p *Params) PreconditionRequired() huma.StatusError {
+	if p.required && len(p.IfMatch) == 0 && p.IfUnmodifiedSince.IsZero() {
+		return huma.NewError(
+			http.StatusPreconditionRequired,
+			http.StatusText(http.StatusPreconditionRequired),
+			&huma.ErrorDetail{
+				Message:  "expected If-Match or If-Unmodified-Since header to be present",
+				Location: "request.headers.If-Match",
+			},
+		)
+	}
+	return nil
+}
+
 // PreconditionFailed returns false if no conditional headers are present, or if
 // the values passed fail based on the conditional read/write rules. See also:
 // https://developer.mozilla.org/en-US/docs/Web/HTTP/Conditional_requests.
@@ -0,0 +1,110 @@
+package conditional
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// metadataRequired is the operation metadata key used to mark operations as
+// requiring a precondition header.
+const metadataRequired = "conditional:required"
+
+// Loader loads the current state of a resource, returning the resource along
+// with its current ETag and/or last modified time. If the resource does not
+// exist, return an empty ETag and zero time so that e.g. `If-None-Match: *`
+// can be used to safely create it.
+type Loader[T any] func(ctx context.Context) (resource T, etag string, modified time.Time, err error)
+
+// Saver saves a resource after all preconditions have passed. It is passed
+// the resource returned by the `Loader`. To fully prevent lost updates the
+// save should itself be atomic with regard to the loaded version, e.g. an
+// `UPDATE ... WHERE version = ?` query.
+type Saver[T any] func(ctx context.Context, resource T) error
+
+// addErrors documents the given error status codes for an operation if not
+// already present.
+func addErrors(op *huma.Operation, codes ...int) {
+outer:
+	for _, code := range codes {
+		for _, existing := range op.Errors {
+			if existing == code {
+				continue outer
+			}
+		}
+		op.Errors = append(op.Errors, code)
+	}
+}
+
+// Document adds the `412 Precondition Failed` response to an operation which
+// uses `conditional.Params` for writes. Operations using `Update` must be
+// wrapped with `Document` or `Require` for the response to be documented, as
+// the handler cannot change the operation once it has been registered.
+//
+//	huma.Register(api, conditional.Document(huma.Operation{
+//		OperationID: "put-thing",
+//		Method:      http.MethodPut,
+//		Path:        "/things/{id}",
+//	}), handler)
+func Document(op huma.Operation) huma.Operation {
+	addErrors(&op, http.StatusPreconditionFailed)
+	return op
+}
+
+// Require configures an operation to require clients to send an `If-Match`
+// or `If-Unmodified-Since` header on writes, preventing blind overwrites.
+// Requests without one get a `428 Precondition Required` from `Update`. The
+// `412 Precondition Failed` and `428 Precondition Required` responses are
+// documented for the operation.
+func Require(op huma.Operation) huma.Operation {
+	addErrors(&op, http.StatusPreconditionFailed, http.StatusPreconditionRequired)
+	if op.Metadata == nil {
+		op.Metadata = map[string]any{}
+	}
+	op.Metadata[metadataRequired] = true
+	return op
+}
+
+// Update performs an optimistic concurrency controlled write. It loads the
+// current resource, enforces the conditional request headers against its
+// ETag and last modified time, then saves the resource if all preconditions
+// pass. It works for any write method, e.g. `PUT`, `PATCH`, or `DELETE`.
+// Failed preconditions return a `412 Precondition Failed` error, and a
+// missing precondition on an operation configured with `conditional.Require`
+// returns a `428 Precondition Required` error. These responses are only
+// documented in the OpenAPI when the operation is wrapped with
+// `conditional.Document` or `conditional.Require`.
+//
+//	func(ctx context.Context, input *struct {
+//		conditional.Params
+//		ID   string `path:"id"`
+//		Body Thing
+//	}) (*struct{}, error) {
+//		return nil, conditional.Update(ctx, &input.Params,
+//			func(ctx context.Context) (*Thing, string, time.Time, error) {
+//				thing, err := db.Get(input.ID)
+//				return thing, thing.Version, thing.Modified, err
+//			},
+//			func(ctx context.Context, thing *Thing) error {
+//				return db.Save(input.ID, input.Body)
+//			},
+//		)
+//	}
+func Update[T any](ctx context.Context, p *Params, load Loader[T], save Saver[T]) error {
+	if err := p.PreconditionRequired(); err != nil {
+		return err
+	}
+
+	resource, etag, modified, err := load(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := p.PreconditionFailed(trimETag(etag), modified); err != nil {
+		return err
+	}
+
+	return save(ctx, resource)
+}
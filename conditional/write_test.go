@@ -0,0 +1,119 @@
+package conditional
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/stretchr/testify/assert"
+)
+
+type thing struct {
+	Name     string
+	Version  string
+	Modified time.Time
+}
+
+func TestUpdate(t *testing.T) {
+	// The default config marshals `application/problem+json` error bodies.
+	_, api := humatest.New(t, huma.DefaultConfig("Test API", "1.0.0"))
+
+	stored := &thing{
+		Name:     "one",
+		Version:  "v1",
+		Modified: time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	type UpdateInput struct {
+		Params
+		Body struct {
+			Name string `json:"name"`
+		}
+	}
+
+	handler := func(ctx context.Context, input *UpdateInput) (*struct{}, error) {
+		return nil, Update(ctx, &input.Params,
+			func(ctx context.Context) (*thing, string, time.Time, error) {
+				if stored == nil {
+					return nil, "", time.Time{}, huma.Error404NotFound("not found")
+				}
+				return stored, `"` + stored.Version + `"`, stored.Modified, nil
+			},
+			func(ctx context.Context, current *thing) error {
+				current.Name = input.Body.Name
+				current.Version = "v2"
+				return nil
+			},
+		)
+	}
+
+	huma.Register(api, Document(huma.Operation{
+		OperationID: "put-optional",
+		Method:      http.MethodPut,
+		Path:        "/optional",
+	}), handler)
+
+	huma.Register(api, Require(huma.Operation{
+		OperationID: "put-required",
+		Method:      http.MethodPut,
+		Path:        "/required",
+	}), handler)
+
+	body := func() *strings.Reader {
+		return strings.NewReader(`{"name": "two"}`)
+	}
+
+	// No precondition is fine when not required.
+	resp := api.Put("/optional", body())
+	assert.Equal(t, http.StatusNoContent, resp.Code, resp.Body.String())
+	assert.Equal(t, "two", stored.Name)
+
+	// Precondition required.
+	resp = api.Put("/required", body())
+	assert.Equal(t, http.StatusPreconditionRequired, resp.Code)
+	assert.Contains(t, resp.Body.String(), "If-Match")
+
+	// Mismatched ETag.
+	resp = api.Put("/required", "If-Match: \"v1\"", body())
+	assert.Equal(t, http.StatusPreconditionFailed, resp.Code)
+
+	// Matching ETag.
+	resp = api.Put("/required", "If-Match: \"v2\"", body())
+	assert.Equal(t, http.StatusNoContent, resp.Code, resp.Body.String())
+
+	// Modified after the given date.
+	resp = api.Put("/required", "If-Unmodified-Since: Wed, 31 May 2023 12:00:00 GMT", body())
+	assert.Equal(t, http.StatusPreconditionFailed, resp.Code)
+
+	resp = api.Put("/required", "If-Unmodified-Since: Thu, 01 Jun 2023 12:00:00 GMT", body())
+	assert.Equal(t, http.StatusNoContent, resp.Code, resp.Body.String())
+
+	// Loader errors are returned as-is.
+	stored = nil
+	resp = api.Put("/required", "If-Match: \"v2\"", body())
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	// Responses are documented.
+	assert.Contains(t, api.OpenAPI().Paths["/optional"].Put.Responses, "412")
+	assert.NotContains(t, api.OpenAPI().Paths["/optional"].Put.Responses, "428")
+	assert.Contains(t, api.OpenAPI().Paths["/required"].Put.Responses, "412")
+	assert.Contains(t, api.OpenAPI().Paths["/required"].Put.Responses, "428")
+}
+
+func TestUpdateSaveError(t *testing.T) {
+	p := &Params{}
+	err := Update(context.Background(), p,
+		func(ctx context.Context) (string, string, time.Time, error) {
+			return "value", "", time.Time{}, nil
+		},
+		func(ctx context.Context, value string) error {
+			return errors.New("save failed")
+		},
+	)
+	assert.EqualError(t, err, "save failed")
+}
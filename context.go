@@ -0,0 +1,114 @@
+package huma
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// humaContext is an alias so embedded contexts do not clash with the
+// `Context()` method of the interface.
+type humaContext = Context
+
+// ContextWrapper wraps a `Context` so that middleware can override some of
+// its methods by embedding the wrapper in a struct. Methods which are not
+// overridden call the wrapped context, available via `Unwrap()`.
+//
+//	type statusContext struct {
+//		huma.ContextWrapper
+//		status int
+//	}
+//
+//	func (c *statusContext) SetStatus(code int) {
+//		c.status = code
+//		c.Unwrap().SetStatus(code)
+//	}
+//
+//	api.UseMiddleware(func(ctx huma.Context, next func(huma.Context)) {
+//		next(&statusContext{ContextWrapper: huma.WrapContext(ctx)})
+//	})
+type ContextWrapper struct {
+	humaContext
+}
+
+// WrapContext returns a wrapper for the context to embed in a struct which
+// overrides some of the context's methods.
+func WrapContext(ctx Context) ContextWrapper {
+	return ContextWrapper{ctx}
+}
+
+// Unwrap returns the wrapped context.
+func (w ContextWrapper) Unwrap() Context {
+	return w.humaContext
+}
+
+// WithContext returns a copy of the context whose `Context()` method returns
+// the given `context.Context`, which is also passed to the operation handler.
+// This lets middleware attach values like tracing spans to the request.
+func WithContext(ctx Context, override context.Context) Context {
+	return &overrideContext{ContextWrapper: WrapContext(ctx), override: override}
+}
+
+// overrideContext replaces the `context.Context` of a request.
+type overrideContext struct {
+	ContextWrapper
+	override context.Context
+}
+
+func (c *overrideContext) Context() context.Context {
+	return c.override
+}
+
+// WithBodyWriter returns a copy of the context whose `BodyWriter()` method
+// returns the given writer, e.g. to capture a marshaled value in a buffer
+// instead of writing it to the client.
+func WithBodyWriter(ctx Context, w io.Writer) Context {
+	return &bodyWriterContext{ContextWrapper: WrapContext(ctx), w: w}
+}
+
+// bodyWriterContext replaces the response body writer of a request.
+type bodyWriterContext struct {
+	ContextWrapper
+	w io.Writer
+}
+
+func (c *bodyWriterContext) BodyWriter() io.Writer {
+	return c.w
+}
+
+// WriterWrapper wraps a response body writer so that middleware can override
+// e.g. `Write` by embedding the wrapper in a struct, while still passing
+// through the optional `http.Flusher`, `SetWriteDeadline`, and
+// `http.Hijacker` interfaces used by streaming responses like the `sse` and
+// `websocket` packages.
+type WriterWrapper struct {
+	io.Writer
+}
+
+// Flush passes through to the wrapped writer, if supported.
+func (w WriterWrapper) Flush() {
+	if f, ok := w.Writer.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// SetWriteDeadline passes through to the wrapped writer, if supported.
+func (w WriterWrapper) SetWriteDeadline(deadline time.Time) error {
+	if d, ok := w.Writer.(interface{ SetWriteDeadline(time.Time) error }); ok {
+		return d.SetWriteDeadline(deadline)
+	}
+	return http.ErrNotSupported
+}
+
+// Hijack passes through to the wrapped writer, if supported. Since the method
+// always exists, callers must check its error to know whether hijacking is
+// actually supported.
+func (w WriterWrapper) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.Writer.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
@@ -0,0 +1,95 @@
+package huma
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type statusContext struct {
+	ContextWrapper
+	status int
+}
+
+func (c *statusContext) SetStatus(code int) {
+	c.status = code
+	c.Unwrap().SetStatus(code)
+}
+
+func TestContextWrapper(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/test?foo=bar", nil)
+	w := httptest.NewRecorder()
+	ctx := &testContext{nil, r, w}
+
+	wrapped := &statusContext{ContextWrapper: WrapContext(ctx)}
+	var _ Context = wrapped
+
+	// Methods which are not overridden call the wrapped context.
+	assert.Equal(t, Context(ctx), wrapped.Unwrap())
+	assert.Equal(t, "bar", wrapped.Query("foo"))
+	assert.Equal(t, http.MethodGet, wrapped.Method())
+
+	wrapped.SetStatus(http.StatusTeapot)
+	assert.Equal(t, http.StatusTeapot, wrapped.status)
+	assert.Equal(t, http.StatusTeapot, w.Code)
+
+	type key struct{}
+	override := WithContext(wrapped, context.WithValue(context.Background(), key{}, "value"))
+	assert.Equal(t, "value", override.Context().Value(key{}))
+	assert.Equal(t, "bar", override.Query("foo"))
+
+	buf := &bytes.Buffer{}
+	captured := WithBodyWriter(wrapped, buf)
+	captured.BodyWriter().Write([]byte("hello"))
+	assert.Equal(t, "hello", buf.String())
+	assert.Empty(t, w.Body.String())
+}
+
+type streamWriter struct {
+	bytes.Buffer
+	flushed  bool
+	deadline time.Time
+}
+
+func (w *streamWriter) Flush() {
+	w.flushed = true
+}
+
+func (w *streamWriter) SetWriteDeadline(deadline time.Time) error {
+	w.deadline = deadline
+	return nil
+}
+
+func (w *streamWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, nil
+}
+
+func TestWriterWrapper(t *testing.T) {
+	out := &streamWriter{}
+	w := WriterWrapper{Writer: out}
+
+	w.Write([]byte("hello"))
+	w.Flush()
+	deadline := time.Now()
+	assert.NoError(t, w.SetWriteDeadline(deadline))
+	_, _, err := w.Hijack()
+	assert.NoError(t, err)
+
+	assert.Equal(t, "hello", out.String())
+	assert.True(t, out.flushed)
+	assert.Equal(t, deadline, out.deadline)
+
+	// Unsupported features return an error rather than panicking.
+	w = WriterWrapper{Writer: &bytes.Buffer{}}
+	w.Flush()
+	assert.ErrorIs(t, w.SetWriteDeadline(deadline), http.ErrNotSupported)
+	_, _, err = w.Hijack()
+	assert.ErrorIs(t, err, http.ErrNotSupported)
+}
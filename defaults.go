@@ -3,6 +3,7 @@ package huma
 import (
 	"encoding/json"
 	"io"
+	"reflect"
 
 	"github.com/fxamacker/cbor/v2"
 )
@@ -34,6 +35,11 @@ var cborEncMode, _ = cbor.EncOptions{
 	TimeTag: cbor.EncTagRequired,
 }.EncMode()
 
+var cborDecMode, _ = cbor.DecOptions{
+	// Decode maps into the same type as JSON so they can be validated.
+	DefaultMapType: reflect.TypeOf(map[string]any{}),
+}.DecMode()
+
 // DefaultCBORFormat is the default CBOR formatter that can be set in the API's
 // `Config.Formats` map. This is used by the `DefaultConfig` function.
 //
@@ -46,15 +52,15 @@ var DefaultCBORFormat = Format{
 	Marshal: func(w io.Writer, v any) error {
 		return cborEncMode.NewEncoder(w).Encode(v)
 	},
-	Unmarshal: cbor.Unmarshal,
+	Unmarshal: cborDecMode.Unmarshal,
 }
 
 // DefaultConfig returns a default configuration for a new API. It is a good
 // starting point for creating your own configuration. It supports JSON and
 // CBOR formats out of the box. The registry uses references for structs and
 // a link transformer is included to add `$schema` fields and links into
-// responses. The `/openapi.[json|yaml]`, `/docs`, and `/schemas` paths are
-// set up to serve the OpenAPI spec, docs UI, and schemas respectively.
+// responses. The `/openapi.[json|yaml]`, `/docs`, and `/schemas` paths are set
+// up to serve the OpenAPI spec, docs UI, and schemas respectively.
 //
 //	// Create and customize the config (if desired).
 //	config := huma.DefaultConfig("My API", "1.0.0")
@@ -3,6 +3,7 @@ package huma
 import (
 	"fmt"
 	"net/http"
+	"reflect"
 	"strconv"
 )
 
@@ -88,6 +89,30 @@ type ErrorModel struct {
 	// Errors provides an optional mechanism of passing additional error details
 	// as a list.
 	Errors []*ErrorDetail `json:"errors,omitempty" doc:"Optional list of individual error details"`
+
+	// Extensions are additional problem-type specific members which are
+	// marshaled inline with the other fields, e.g. `balance` for an
+	// `out-of-credit` problem type. Members which would replace a standard
+	// member, e.g. `status`, are skipped. See `huma.ProblemType`.
+	Extensions map[string]any `json:"-" cbor:"-"`
+}
+
+// errorModel is used to marshal the error model without recursing into its
+// custom marshaling methods.
+type errorModel ErrorModel
+
+// MarshalJSON marshals the error, including any extension members inline.
+func (e *ErrorModel) MarshalJSON() ([]byte, error) {
+	return (&withExtensions{(*errorModel)(e), e.Extensions}).MarshalJSON()
+}
+
+// MarshalCBOR marshals the error, including any extension members inline.
+func (e *ErrorModel) MarshalCBOR() ([]byte, error) {
+	return (&withExtensions{(*errorModel)(e), e.Extensions}).MarshalCBOR()
+}
+
+func (e *ErrorModel) extensionMembers() map[string]any {
+	return e.Extensions
 }
 
 // Error satisfies the `error` interface. It returns the error's detail field.
@@ -153,7 +178,9 @@ type StatusError interface {
 // message, and optional error details. If the error details implement the
 // `ErrorDetailer` interface, the error details will be used. Otherwise, the
 // error string will be used as the message. This function is used by all the
-// error response utility functions, like `huma.Error400BadRequest`.
+// error response utility functions, like `huma.Error400BadRequest`. If a
+// default problem type has been registered for the status code in
+// `huma.Problems`, then its type URI and title are used.
 //
 // Replace this function to use your own error type. Example:
 //
@@ -192,12 +219,17 @@ var NewError = func(status int, msg string, errs ...error) StatusError {
 			details[i] = &ErrorDetail{Message: errs[i].Error()}
 		}
 	}
-	return &ErrorModel{
+	model := &ErrorModel{
 		Status: status,
 		Title:  http.StatusText(status),
 		Detail: msg,
 		Errors: details,
 	}
+	if pt := Problems.ForStatus(status); pt != nil {
+		model.Type = pt.URI
+		model.Title = pt.Title
+	}
+	return model
 }
 
 // WriteErr writes an error response with the given context, using the
@@ -217,7 +249,40 @@ func WriteErr(api API, ctx Context, status int, msg string, errs ...error) error
 
 	ctx.SetHeader("Content-Type", ct)
 	ctx.SetStatus(status)
-	return api.Marshal(ctx, strconv.Itoa(status), ct, err)
+	if merr := api.Marshal(ctx, strconv.Itoa(status), ct, err); merr != nil {
+		RequestLogger(api, ctx).Error("unable to marshal error response", "status", status, "error", merr)
+		return merr
+	}
+	return nil
+}
+
+// errorContent returns the error type, its content type, and its schema as
+// documented for error responses.
+func errorContent(registry Registry) (reflect.Type, string, *Schema) {
+	exampleErr := NewError(0, "")
+	contentType := "application/json"
+	if ctf, ok := exampleErr.(ContentTypeFilter); ok {
+		contentType = ctf.ContentType(contentType)
+	}
+	errType := reflect.TypeOf(exampleErr)
+	return errType, contentType, registry.Schema(errType, true, getHint(errType, "", "Error"))
+}
+
+// ErrorResponse returns the OpenAPI response documenting the configured
+// error type for the given status code, the same as `Register` generates for
+// `Operation.Errors`, including any problem type extension members for the
+// status. This is useful for middleware which returns its own errors.
+func ErrorResponse(registry Registry, status int) *Response {
+	errType, contentType, errSchema := errorContent(registry)
+	return &Response{
+		Description: http.StatusText(status),
+		Content: map[string]*MediaType{
+			contentType: {
+				// Document any problem type extension members for this status.
+				Schema: Problems.schema(registry, errType, errSchema, status),
+			},
+		},
+	}
 }
 
 // Status304NotModified returns a 304. This is not really an error, but
@@ -0,0 +1,131 @@
+package huma
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ETagger is implemented by operation output structs which can cheaply
+// provide the current entity tag of a resource. When `Operation.AutoETag` is
+// enabled this avoids rendering and hashing the response body, and lets a
+// `304 Not Modified` be returned without marshaling anything.
+//
+//	func (o *GetThingOutput) ETag() string {
+//		return o.Body.Version
+//	}
+type ETagger interface {
+	ETag() string
+}
+
+// LastModifier is implemented by operation output structs which can provide
+// the last modified time of a resource. When `Operation.AutoETag` is enabled
+// this is sent as the `Last-Modified` header and used to handle
+// `If-Modified-Since` requests.
+type LastModifier interface {
+	LastModified() time.Time
+}
+
+var lastModifierType = reflect.TypeOf((*LastModifier)(nil)).Elem()
+
+// quoteETag ensures an entity tag is quoted as required by RFC 9110, leaving
+// weak or already quoted values as-is.
+func quoteETag(etag string) string {
+	if etag == "" || strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`) {
+		return etag
+	}
+	return `"` + etag + `"`
+}
+
+// hashETag generates a strong entity tag from a response body.
+func hashETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatch returns true if any entity tag in an `If-None-Match` header
+// matches the given entity tag using the weak comparison function.
+func etagMatch(header, etag string) bool {
+	opaque := strings.TrimPrefix(etag, "W/")
+	for header != "" {
+		var tag string
+		tag, header, _ = strings.Cut(header, ",")
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == opaque {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified returns true if a read request's conditional headers indicate
+// the client already has the current representation. As per RFC 9110,
+// `If-Modified-Since` is ignored when `If-None-Match` is present.
+func notModified(ctx Context, etag string, modified time.Time) bool {
+	if inm := ctx.Header("If-None-Match"); inm != "" {
+		return etag != "" && etagMatch(inm, etag)
+	}
+	if ims := ctx.Header("If-Modified-Since"); ims != "" && !modified.IsZero() {
+		if t, err := http.ParseTime(ims); err == nil {
+			return !modified.Truncate(time.Second).After(t)
+		}
+	}
+	return false
+}
+
+// documentAutoETag adds the conditional request parameters, response headers,
+// and `304 Not Modified` response to an operation using `AutoETag`.
+func documentAutoETag(op *Operation, lastModified bool) {
+	params := []string{"If-None-Match"}
+	if lastModified {
+		params = append(params, "If-Modified-Since")
+	}
+	for _, name := range params {
+		found := false
+		for _, p := range op.Parameters {
+			if p.In == "header" && strings.EqualFold(p.Name, name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			op.Parameters = append(op.Parameters, &Param{
+				Name:   name,
+				In:     "header",
+				Schema: &Schema{Type: TypeString},
+			})
+		}
+	}
+
+	if resp := op.Responses["200"]; resp != nil {
+		if resp.Headers == nil {
+			resp.Headers = map[string]*Param{}
+		}
+		if !hasHeader(resp.Headers, "ETag") {
+			resp.Headers["ETag"] = &Header{Schema: &Schema{Type: TypeString}}
+		}
+		if lastModified && !hasHeader(resp.Headers, "Last-Modified") {
+			resp.Headers["Last-Modified"] = &Header{Schema: &Schema{Type: TypeString}}
+		}
+	}
+
+	if op.Responses["304"] == nil {
+		op.Responses["304"] = &Response{
+			Description: http.StatusText(http.StatusNotModified),
+		}
+	}
+}
+
+// hasHeader returns whether the response headers include the given name,
+// which may be documented using any case.
+func hasHeader(headers map[string]*Param, name string) bool {
+	for k := range headers {
+		if strings.EqualFold(k, name) {
+			return true
+		}
+	}
+	return false
+}
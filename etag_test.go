@@ -0,0 +1,172 @@
+package huma
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type etagBody struct {
+	Name string `json:"name"`
+}
+
+type cheapOutput struct {
+	Body etagBody
+}
+
+func (o *cheapOutput) ETag() string {
+	return "v1"
+}
+
+func (o *cheapOutput) LastModified() time.Time {
+	return time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+}
+
+func TestAutoETag(t *testing.T) {
+	// Count how many times response bodies are rendered.
+	renders := 0
+	config := DefaultConfig("Test API", "1.0.0")
+	config.Transformers = append(config.Transformers, func(ctx Context, status string, v any) (any, error) {
+		renders++
+		return v, nil
+	})
+
+	r := chi.NewRouter()
+	api := NewTestAdapter(r, config)
+
+	Register(api, Operation{
+		OperationID: "get-hashed",
+		Method:      http.MethodGet,
+		Path:        "/hashed",
+		AutoETag:    true,
+	}, func(ctx context.Context, input *struct{}) (*struct{ Body etagBody }, error) {
+		return &struct{ Body etagBody }{Body: etagBody{Name: "hashed"}}, nil
+	})
+
+	Register(api, Operation{
+		OperationID: "get-cheap",
+		Method:      http.MethodGet,
+		Path:        "/cheap",
+		AutoETag:    true,
+	}, func(ctx context.Context, input *struct{}) (*cheapOutput, error) {
+		return &cheapOutput{Body: etagBody{Name: "cheap"}}, nil
+	})
+
+	Register(api, Operation{
+		OperationID: "get-bytes",
+		Method:      http.MethodGet,
+		Path:        "/bytes",
+		AutoETag:    true,
+	}, func(ctx context.Context, input *struct{}) (*struct{ Body []byte }, error) {
+		return &struct{ Body []byte }{Body: []byte("hello")}, nil
+	})
+
+	do := func(path string, headers ...string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		for i := 0; i < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// Generated from the body hash.
+	w := do("/hashed")
+	assert.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Contains(t, w.Body.String(), `"name":"hashed"`)
+
+	w = do("/hashed", "If-None-Match", `"other", `+etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+	assert.Empty(t, w.Body.String())
+
+	w = do("/hashed", "If-None-Match", "W/"+etag)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = do("/hashed", "If-None-Match", `"other"`)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Cheap output values skip rendering entirely.
+	renders = 0
+	w = do("/cheap", "If-None-Match", `"v1"`)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Equal(t, `"v1"`, w.Header().Get("ETag"))
+	assert.Equal(t, "Thu, 01 Jun 2023 12:00:00 GMT", w.Header().Get("Last-Modified"))
+	assert.Equal(t, 0, renders)
+
+	w = do("/cheap", "If-Modified-Since", "Thu, 01 Jun 2023 12:00:00 GMT")
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = do("/cheap", "If-Modified-Since", "Wed, 31 May 2023 12:00:00 GMT")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, renders)
+
+	// If-None-Match takes precedence over If-Modified-Since.
+	w = do("/cheap", "If-None-Match", `"v0"`, "If-Modified-Since", "Thu, 01 Jun 2023 12:00:00 GMT")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Raw byte bodies are hashed directly.
+	w = do("/bytes")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "hello", w.Body.String())
+	w = do("/bytes", "If-None-Match", w.Header().Get("ETag"))
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	// Documented in the OpenAPI.
+	op := api.OpenAPI().Paths["/cheap"].Get
+	assert.Contains(t, op.Responses, "304")
+	assert.Contains(t, op.Responses["200"].Headers, "ETag")
+	assert.Contains(t, op.Responses["200"].Headers, "Last-Modified")
+	names := mapTo(op.Parameters, func(p *Param) string { return p.Name })
+	assert.ElementsMatch(t, []string{"If-None-Match", "If-Modified-Since"}, names)
+	assert.NotContains(t, api.OpenAPI().Paths["/hashed"].Get.Responses["200"].Headers, "Last-Modified")
+}
+
+func TestAutoETagHeaderCase(t *testing.T) {
+	r := chi.NewRouter()
+	api := NewTestAdapter(r, DefaultConfig("Test API", "1.0.0"))
+
+	// Header names in tags are not necessarily canonical.
+	type Output struct {
+		ETag     string    `header:"Etag"`
+		Modified time.Time `header:"last-modified"`
+		Body     etagBody
+	}
+	Register(api, Operation{
+		OperationID: "get-tagged",
+		Method:      http.MethodGet,
+		Path:        "/tagged",
+		AutoETag:    true,
+	}, func(ctx context.Context, input *struct{}) (*Output, error) {
+		return &Output{
+			ETag:     `"v1"`,
+			Modified: time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC),
+			Body:     etagBody{Name: "tagged"},
+		}, nil
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/tagged", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{`"v1"`}, w.Header().Values("ETag"))
+
+	req, _ = http.NewRequest(http.MethodGet, "/tagged", nil)
+	req.Header.Set("If-Modified-Since", "Thu, 01 Jun 2023 12:00:00 GMT")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	headers := api.OpenAPI().Paths["/tagged"].Get.Responses["200"].Headers
+	assert.Len(t, headers, 2)
+	assert.Contains(t, headers, "Etag")
+	assert.Contains(t, headers, "last-modified")
+}
@@ -3,6 +3,7 @@ package huma
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"net"
@@ -475,6 +476,32 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 		}
 	}
 
+	if op.Timeout == 0 {
+		op.Timeout = api.Config().Timeout
+	}
+	if op.TimeoutStatus == 0 {
+		op.TimeoutStatus = http.StatusServiceUnavailable
+	}
+	// Streaming responses may legitimately run for a long time, so they are
+	// never subject to the timeout.
+	timeout := op.Timeout
+	if outBodyFunc {
+		timeout = 0
+	}
+	if timeout > 0 && !slices.Contains(op.Errors, op.TimeoutStatus) {
+		op.Errors = append(op.Errors, op.TimeoutStatus)
+	}
+
+	if op.IdempotencyKeyRequired {
+		op.IdempotencyKey = true
+	}
+	if op.IdempotencyKey {
+		if outBodyFunc {
+			panic("idempotency keys are not supported for streaming responses")
+		}
+		documentIdempotency(&op)
+	}
+
 	if len(op.Errors) > 0 && (len(inputParams.Paths) > 0 || inputBodyIndex >= -1) {
 		op.Errors = append(op.Errors, http.StatusUnprocessableEntity)
 	}
@@ -482,22 +509,9 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 		op.Errors = append(op.Errors, http.StatusInternalServerError)
 	}
 
-	exampleErr := NewError(0, "")
-	errContentType := "application/json"
-	if ctf, ok := exampleErr.(ContentTypeFilter); ok {
-		errContentType = ctf.ContentType(errContentType)
-	}
-	errType := reflect.TypeOf(exampleErr)
-	errSchema := registry.Schema(errType, true, getHint(errType, "", "Error"))
+	_, errContentType, errSchema := errorContent(registry)
 	for _, code := range op.Errors {
-		op.Responses[fmt.Sprintf("%d", code)] = &Response{
-			Description: http.StatusText(code),
-			Content: map[string]*MediaType{
-				errContentType: {
-					Schema: errSchema,
-				},
-			},
-		}
+		op.Responses[fmt.Sprintf("%d", code)] = ErrorResponse(registry, code)
 	}
 	if len(op.Responses) <= 1 && len(op.Errors) == 0 {
 		// No errors are defined, so set a default response.
@@ -511,15 +525,24 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 		}
 	}
 
+	if op.AutoETag && (op.Method == http.MethodGet || op.Method == http.MethodHead) {
+		documentAutoETag(&op, reflect.PointerTo(outputType).Implements(lastModifierType))
+	}
+
 	if !op.Hidden {
 		oapi.AddOperation(&op)
 	}
 
 	a := api.Adapter()
 
-	a.Handle(&op, func(ctx Context) {
+	op.handler = func(ctx Context) {
 		var input I
 
+		ph := newPhases(ctx)
+		defer ph.done(nil)
+		defer recoverPanic(api, ctx, &ph)
+		ph.start(PhaseParams)
+
 		// Get the validation dependencies from the shared pool.
 		deps := validatePool.Get().(*validateDeps)
 		defer func() {
@@ -530,7 +553,11 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 		pb := deps.pb
 		res := deps.res
 
+		// Select the message catalog to use for any validation errors.
+		res.Locale = SelectLocale(ctx.Header("Accept-Language"))
+
 		errStatus := http.StatusUnprocessableEntity
+		var bodySum []byte
 
 		v := reflect.ValueOf(&input).Elem()
 		inputParams.Every(v, func(f reflect.Value, p *paramFieldInfo) {
@@ -554,7 +581,7 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 
 			if p.Loc == "path" && value == "" {
 				// Path params are always required.
-				res.Add(pb, "", "required path parameter is missing")
+				res.Add(pb, "", MsgRequiredPathParam)
 				return
 			}
 
@@ -568,7 +595,7 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 				case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 					v, err := strconv.ParseInt(value, 10, 64)
 					if err != nil {
-						res.Add(pb, value, "invalid integer")
+						res.Add(pb, value, MsgInvalidInteger)
 						return
 					}
 					f.SetInt(v)
@@ -576,7 +603,7 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 				case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 					v, err := strconv.ParseUint(value, 10, 64)
 					if err != nil {
-						res.Add(pb, value, "invalid integer")
+						res.Add(pb, value, MsgInvalidInteger)
 						return
 					}
 					f.SetUint(v)
@@ -584,7 +611,7 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 				case reflect.Float32, reflect.Float64:
 					v, err := strconv.ParseFloat(value, 64)
 					if err != nil {
-						res.Add(pb, value, "invalid float")
+						res.Add(pb, value, MsgInvalidFloat)
 						return
 					}
 					f.SetFloat(v)
@@ -592,7 +619,7 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 				case reflect.Bool:
 					v, err := strconv.ParseBool(value)
 					if err != nil {
-						res.Add(pb, value, "invalid boolean")
+						res.Add(pb, value, MsgInvalidBoolean)
 						return
 					}
 					f.SetBool(v)
@@ -610,7 +637,7 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 					if f.Type() == timeType {
 						t, err := time.Parse(p.TimeFormat, value)
 						if err != nil {
-							res.Add(pb, value, "invalid date/time for format "+p.TimeFormat)
+							res.Addf(pb, value, MsgInvalidDateTime, p.TimeFormat)
 							return
 						}
 						f.Set(reflect.ValueOf(t))
@@ -629,11 +656,18 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 
 		// Read input body if defined.
 		if inputBodyIndex != -1 {
+			ph.start(PhaseBody)
+			var deadlineErr error
 			if op.BodyReadTimeout > 0 {
-				ctx.SetReadDeadline(time.Now().Add(op.BodyReadTimeout))
+				deadlineErr = ctx.SetReadDeadline(time.Now().Add(op.BodyReadTimeout))
 			} else if op.BodyReadTimeout < 0 {
 				// Disable any server-wide deadline.
-				ctx.SetReadDeadline(time.Time{})
+				deadlineErr = ctx.SetReadDeadline(time.Time{})
+			}
+			if deadlineErr != nil {
+				// Many writers do not support deadlines, so avoid flooding the
+				// logs with one message per request.
+				RequestLogger(api, ctx).Debug("unable to set read deadline", "error", deadlineErr)
 			}
 
 			buf := bufPool.Get().(*bytes.Buffer)
@@ -652,23 +686,32 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 				if count == op.MaxBodyBytes {
 					buf.Reset()
 					bufPool.Put(buf)
-					WriteErr(api, ctx, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body is too large limit=%d bytes", op.MaxBodyBytes), res.Errors...)
+					WriteErr(api, ctx, http.StatusRequestEntityTooLarge, fmt.Sprintf(Msg(res.Locale, MsgRequestBodyTooLarge), op.MaxBodyBytes), res.Errors...)
 					return
 				}
 			}
 			if err != nil {
 				buf.Reset()
 				bufPool.Put(buf)
+				ph.done(err)
 
 				if e, ok := err.(net.Error); ok && e.Timeout() {
-					WriteErr(api, ctx, http.StatusRequestTimeout, "request body read timeout", res.Errors...)
+					WriteErr(api, ctx, http.StatusRequestTimeout, Msg(res.Locale, MsgRequestBodyTimeout), res.Errors...)
 					return
 				}
 
-				WriteErr(api, ctx, http.StatusInternalServerError, "cannot read request body", err)
+				if ctx.Context().Err() != nil {
+					RequestLogger(api, ctx).Info("client disconnected while reading request body", "error", err)
+				}
+				WriteErr(api, ctx, http.StatusInternalServerError, Msg(res.Locale, MsgRequestBodyRead), err)
 				return
 			}
 			body := buf.Bytes()
+			if op.IdempotencyKey {
+				sum := sha256.Sum256(body)
+				bodySum = sum[:]
+			}
+			ph.start(PhaseValidate)
 
 			if rawBodyIndex != -1 {
 				f := v.Field(rawBodyIndex)
@@ -680,7 +723,7 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 				if kind != reflect.Ptr && kind != reflect.Interface {
 					buf.Reset()
 					bufPool.Put(buf)
-					WriteErr(api, ctx, http.StatusBadRequest, "request body is required", res.Errors...)
+					WriteErr(api, ctx, http.StatusBadRequest, Msg(res.Locale, MsgRequestBodyRequired), res.Errors...)
 					return
 				}
 			} else {
@@ -740,6 +783,9 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 			}
 		}
 
+		if inputBodyIndex == -1 {
+			ph.start(PhaseValidate)
+		}
 		resolvers.EveryPB(pb, v, func(item reflect.Value, _ bool) {
 			if resolver, ok := item.Addr().Interface().(Resolver); ok {
 				if errs := resolver.Resolve(ctx); len(errs) > 0 {
@@ -755,12 +801,48 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 		})
 
 		if len(res.Errors) > 0 {
-			WriteErr(api, ctx, errStatus, "validation failed", res.Errors...)
+			ph.fail(res.Errors)
+			WriteErr(api, ctx, errStatus, Msg(res.Locale, MsgValidationFailed), res.Errors...)
 			return
 		}
 
-		output, err := handler(ctx.Context(), &input)
+		// running is closed once a handler abandoned after a timeout returns.
+		var running <-chan struct{}
+		if op.IdempotencyKey {
+			idemCtx := startIdempotency(api, ctx, &op, bodySum)
+			if idemCtx == nil {
+				// The response was replayed or the request rejected.
+				return
+			}
+			if ic, ok := idemCtx.(*idempotentContext); ok {
+				ctx = ic
+				defer func() {
+					if r := recover(); r != nil {
+						ic.release(nil)
+						panic(r)
+					}
+					if running != nil {
+						// Keep the key reserved while the handler may still
+						// apply its side effects, so retries get a conflict.
+						ic.releaseAfter(running)
+						return
+					}
+					ic.finish()
+				}()
+			}
+		}
+
+		ph.start(PhaseHandler)
+		var output *O
+		var err error
+		if timeout > 0 {
+			output, running, err = callWithTimeout(ctx.Context(), timeout, op.TimeoutStatus, handler, &input)
+		} else {
+			output, err = handler(ctx.Context(), &input)
+		}
 		if err != nil {
+			ph.done(err)
+			ph.start(PhaseMarshal)
 			status := http.StatusInternalServerError
 			if se, ok := err.(StatusError); ok {
 				status = se.GetStatus()
@@ -775,20 +857,30 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 
 			ctx.SetStatus(status)
 			ctx.SetHeader("Content-Type", ct)
-			api.Marshal(ctx, strconv.Itoa(status), ct, err)
+			if merr := api.Marshal(ctx, strconv.Itoa(status), ct, err); merr != nil {
+				ph.done(merr)
+				RequestLogger(api, ctx).Error("unable to marshal error response", "status", status, "error", merr)
+			}
 			return
 		}
+		ph.start(PhaseMarshal)
 
 		// Serialize output headers
 		ct := ""
+		etag := ""
+		var modified time.Time
 		vo := reflect.ValueOf(output).Elem()
 		outHeaders.Every(vo, func(f reflect.Value, info *headerInfo) {
 			switch f.Kind() {
 			case reflect.String:
 				ctx.SetHeader(info.Name, f.String())
-				if info.Name == "Content-Type" {
+				// Header names in tags need not be canonical.
+				if strings.EqualFold(info.Name, "Content-Type") {
 					ct = f.String()
 				}
+				if strings.EqualFold(info.Name, "ETag") {
+					etag = f.String()
+				}
 			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 				ctx.SetHeader(info.Name, strconv.FormatInt(f.Int(), 10))
 			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
@@ -800,6 +892,9 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 			default:
 				if f.Type() == timeType {
 					ctx.SetHeader(info.Name, f.Interface().(time.Time).Format(info.TimeFormat))
+					if strings.EqualFold(info.Name, "Last-Modified") {
+						modified = f.Interface().(time.Time)
+					}
 					return
 				}
 
@@ -812,6 +907,28 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 			status = int(vo.Field(outStatusIndex).Int())
 		}
 
+		autoETag := op.AutoETag && status == http.StatusOK && (ctx.Method() == http.MethodGet || ctx.Method() == http.MethodHead)
+		if autoETag {
+			// Use cheap values from the output when available so the body need
+			// not be rendered at all if the client's copy is current.
+			if etag == "" {
+				if e, ok := any(output).(ETagger); ok {
+					if etag = quoteETag(e.ETag()); etag != "" {
+						ctx.SetHeader("ETag", etag)
+					}
+				}
+			}
+			if m, ok := any(output).(LastModifier); ok && modified.IsZero() {
+				if modified = m.LastModified(); !modified.IsZero() {
+					ctx.SetHeader("Last-Modified", modified.UTC().Format(http.TimeFormat))
+				}
+			}
+			if (etag != "" || !modified.IsZero()) && notModified(ctx, etag, modified) {
+				ctx.SetStatus(http.StatusNotModified)
+				return
+			}
+		}
+
 		if outBodyIndex != -1 {
 			// Serialize output body
 			body := vo.Field(outBodyIndex).Interface()
@@ -822,6 +939,14 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 			}
 
 			if b, ok := body.([]byte); ok {
+				if autoETag && etag == "" {
+					etag = hashETag(b)
+					ctx.SetHeader("ETag", etag)
+					if notModified(ctx, etag, modified) {
+						ctx.SetStatus(http.StatusNotModified)
+						return
+					}
+				}
 				ctx.SetStatus(status)
 				ctx.BodyWriter().Write(b)
 				return
@@ -842,12 +967,42 @@ func Register[I, O any](api API, op Operation, handler func(context.Context, *I)
 				ctx.SetHeader("Content-Type", ct)
 			}
 
+			if autoETag && etag == "" {
+				// Render the body to generate a strong ETag from its hash.
+				buf := bufPool.Get().(*bytes.Buffer)
+				if err := api.Marshal(WithBodyWriter(ctx, buf), strconv.Itoa(op.DefaultStatus), ct, body); err != nil {
+					buf.Reset()
+					bufPool.Put(buf)
+					ph.done(err)
+					RequestLogger(api, ctx).Error("unable to marshal response", "error", err)
+					WriteErr(api, ctx, http.StatusInternalServerError, "unable to marshal response", err)
+					return
+				}
+				etag = hashETag(buf.Bytes())
+				ctx.SetHeader("ETag", etag)
+				if notModified(ctx, etag, modified) {
+					ctx.SetStatus(http.StatusNotModified)
+				} else {
+					ctx.SetStatus(status)
+					ctx.BodyWriter().Write(buf.Bytes())
+				}
+				buf.Reset()
+				bufPool.Put(buf)
+				return
+			}
+
 			ctx.SetStatus(status)
-			api.Marshal(ctx, strconv.Itoa(op.DefaultStatus), ct, body)
+			if err := api.Marshal(ctx, strconv.Itoa(op.DefaultStatus), ct, body); err != nil {
+				// The status has already been sent, so all we can do is log.
+				ph.done(err)
+				RequestLogger(api, ctx).Error("unable to marshal response", "status", status, "error", err)
+			}
 		} else {
 			ctx.SetStatus(status)
 		}
-	})
+	}
+
+	a.Handle(&op, api.Middlewares().Handler(op.Middlewares.Handler(op.handler)))
 }
 
 // AutoRegister auto-detects operation registration methods and registers them
@@ -47,6 +47,10 @@ func (c *testContext) Host() string {
 	return c.r.Host
 }
 
+func (c *testContext) RemoteAddr() string {
+	return c.r.RemoteAddr
+}
+
 func (c *testContext) URL() url.URL {
 	return *c.r.URL
 }
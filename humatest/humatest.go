@@ -54,6 +54,10 @@ func (c *testContext) Host() string {
 	return c.r.Host
 }
 
+func (c *testContext) RemoteAddr() string {
+	return c.r.RemoteAddr
+}
+
 func (c *testContext) URL() url.URL {
 	return *c.r.URL
 }
@@ -181,6 +185,8 @@ func (a *testAPI) Do(method, path string, args ...any) *httptest.ResponseRecorde
 	}
 
 	req, _ := http.NewRequest(method, path, b)
+	// Set a client address like a real server would, matching `httptest`.
+	req.RemoteAddr = "192.0.2.1:1234"
 	for _, arg := range args {
 		if s, ok := arg.(string); ok {
 			parts := strings.Split(s, ":")
@@ -239,7 +245,8 @@ func Wrap(tb TB, api huma.API) TestAPI {
 // New creates a new router and test API, making it easy to register operations
 // and perform requests against them. Optionally takes a configuration object
 // to customize how the API is created. If no configuration is provided then
-// a simple default configuration supporting `application/json` is used.
+// a simple default configuration supporting `application/json` and JSON-based
+// formats like `application/problem+json` is used.
 func New(tb TB, configs ...huma.Config) (chi.Router, TestAPI) {
 	if len(configs) == 0 {
 		configs = append(configs, huma.Config{
@@ -251,6 +258,10 @@ func New(tb TB, configs ...huma.Config) (chi.Router, TestAPI) {
 			},
 			Formats: map[string]huma.Format{
 				"application/json": huma.DefaultJSONFormat,
+				// Errors are marshaled as `application/problem+json`, which is
+				// only matched by the `json` suffix format. Without it error
+				// response bodies would be empty.
+				"json": huma.DefaultJSONFormat,
 			},
 			DefaultFormat: "application/json",
 		})
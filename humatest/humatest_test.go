@@ -53,6 +53,22 @@ func TestHumaTestUtils(t *testing.T) {
 	assert.JSONEq(t, `{"echo":"hello"}`, w.Body.String())
 }
 
+func TestErrorBody(t *testing.T) {
+	_, api := New(t)
+
+	huma.Register(api, huma.Operation{
+		Method: http.MethodGet,
+		Path:   "/missing",
+	}, func(ctx context.Context, input *struct{}) (*struct{}, error) {
+		return nil, huma.Error404NotFound("not here")
+	})
+
+	w := api.Get("/missing")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "not here")
+}
+
 func TestContext(t *testing.T) {
 	op := &huma.Operation{}
 	r, _ := http.NewRequest(http.MethodGet, "/", nil)
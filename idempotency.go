@@ -0,0 +1,309 @@
+package huma
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+// Idempotency headers.
+const (
+	// HeaderIdempotencyKey is the request header clients use to safely retry
+	// unsafe requests, as per the IETF `Idempotency-Key` header draft.
+	HeaderIdempotencyKey = "Idempotency-Key"
+
+	// HeaderIdempotentReplayed is set to `true` on responses which were
+	// replayed from the store rather than produced by the handler.
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+)
+
+// MaxIdempotencyKeyLength is the maximum length of an `Idempotency-Key`
+// header value. Longer keys are rejected with a `400 Bad Request`.
+var MaxIdempotencyKeyLength = 255
+
+var (
+	// ErrIdempotencyInProgress is returned by an `IdempotencyStore` when a
+	// request with the same key has started but not yet finished.
+	ErrIdempotencyInProgress = errors.New("a request with this idempotency key is in progress")
+
+	// ErrIdempotencyKeyReused is returned by an `IdempotencyStore` when a key
+	// is reused for a request with a different fingerprint.
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different request")
+)
+
+// IdempotentResponse is a response stored for an idempotency key.
+type IdempotentResponse struct {
+	Status  int
+	Headers http.Header
+	Body    []byte
+}
+
+// IdempotencyStore records responses for operations using
+// `Operation.IdempotencyKey`. Implementations must be safe for concurrent
+// use. Use a shared store such as Redis when running multiple instances.
+type IdempotencyStore interface {
+	// Start reserves the key for a request with the given fingerprint. If a
+	// response has been saved for the key and fingerprint it is returned. It
+	// returns `ErrIdempotencyInProgress` if the key is reserved by another
+	// request, and `ErrIdempotencyKeyReused` if the key was used with a
+	// different fingerprint.
+	Start(ctx context.Context, key, fingerprint string) (*IdempotentResponse, error)
+
+	// Finish saves the response for a key reserved by `Start`. A nil response
+	// releases the key without saving anything so the request can be retried.
+	Finish(ctx context.Context, key string, resp *IdempotentResponse) error
+}
+
+// idempotencyEntry is the state of a single key in the memory store. A nil
+// response means the request is still in progress.
+type idempotencyEntry struct {
+	fingerprint string
+	resp        *IdempotentResponse
+	expires     time.Time
+}
+
+// MemoryIdempotencyStore is an in-memory `IdempotencyStore` for a single
+// instance. Keys expire after a TTL, including keys of in-progress requests
+// so a crashed request does not reserve a key forever.
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*idempotencyEntry
+	swept   time.Time
+	now     func() time.Time
+}
+
+// NewMemoryIdempotencyStore creates a new in-memory store which keeps keys
+// for the given duration.
+func NewMemoryIdempotencyStore(ttl time.Duration) *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		ttl:     ttl,
+		entries: map[string]*idempotencyEntry{},
+		now:     time.Now,
+	}
+}
+
+// Start reserves the key or returns its saved response.
+func (s *MemoryIdempotencyStore) Start(ctx context.Context, key, fingerprint string) (*IdempotentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.swept) >= s.ttl {
+		for k, e := range s.entries {
+			if now.After(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+		s.swept = now
+	}
+
+	if e := s.entries[key]; e != nil && now.Before(e.expires) {
+		if e.fingerprint != fingerprint {
+			return nil, ErrIdempotencyKeyReused
+		}
+		if e.resp == nil {
+			return nil, ErrIdempotencyInProgress
+		}
+		return e.resp, nil
+	}
+
+	s.entries[key] = &idempotencyEntry{
+		fingerprint: fingerprint,
+		expires:     now.Add(s.ttl),
+	}
+	return nil, nil
+}
+
+// Finish saves the response for the key, or releases it if nil.
+func (s *MemoryIdempotencyStore) Finish(ctx context.Context, key string, resp *IdempotentResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := s.entries[key]
+	if e == nil {
+		return nil
+	}
+	if resp == nil {
+		delete(s.entries, key)
+		return nil
+	}
+	e.resp = resp
+	e.expires = s.now().Add(s.ttl)
+	return nil
+}
+
+// idempotencyFingerprint identifies a request by its method, URI, and body
+// hash so a reused key can be detected.
+func idempotencyFingerprint(ctx Context, bodySum []byte) string {
+	u := ctx.URL()
+	h := sha256.New()
+	h.Write([]byte(ctx.Method() + " " + u.RequestURI() + "\n"))
+	h.Write(bodySum)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// startIdempotency handles the `Idempotency-Key` header for a request. If the
+// response has been fully written, e.g. a replay or a conflict, it returns
+// nil. Otherwise it returns a context which records the response, or the
+// original context if the request has no key.
+func startIdempotency(api API, ctx Context, op *Operation, bodySum []byte) Context {
+	key := ctx.Header(HeaderIdempotencyKey)
+	if key == "" {
+		if op.IdempotencyKeyRequired {
+			WriteErr(api, ctx, http.StatusBadRequest, HeaderIdempotencyKey+" header is required")
+			return nil
+		}
+		return ctx
+	}
+	if len(key) > MaxIdempotencyKeyLength {
+		WriteErr(api, ctx, http.StatusBadRequest, HeaderIdempotencyKey+" header is too long")
+		return nil
+	}
+
+	config := api.Config()
+	store := config.IdempotencyStore
+	if config.IdempotencyScope != nil {
+		// Quoted so the scope and key cannot run together ambiguously.
+		key = strconv.Quote(config.IdempotencyScope(ctx)) + " " + key
+	}
+	key = op.Method + " " + op.Path + " " + key
+	resp, err := store.Start(ctx.Context(), key, idempotencyFingerprint(ctx, bodySum))
+	switch {
+	case errors.Is(err, ErrIdempotencyInProgress):
+		WriteErr(api, ctx, http.StatusConflict, err.Error())
+		return nil
+	case errors.Is(err, ErrIdempotencyKeyReused):
+		WriteErr(api, ctx, http.StatusUnprocessableEntity, err.Error())
+		return nil
+	case err != nil:
+		// Running the request without protection could apply it twice.
+		RequestLogger(api, ctx).Error("unable to check idempotency key", "error", err)
+		WriteErr(api, ctx, http.StatusInternalServerError, "unable to check idempotency key")
+		return nil
+	case resp != nil:
+		for name, values := range resp.Headers {
+			for _, value := range values {
+				ctx.AppendHeader(name, value)
+			}
+		}
+		ctx.SetHeader(HeaderIdempotentReplayed, "true")
+		ctx.SetStatus(resp.Status)
+		ctx.BodyWriter().Write(resp.Body)
+		return nil
+	}
+
+	return &idempotentContext{
+		ContextWrapper: WrapContext(ctx),
+		store:          store,
+		key:            key,
+		headers:        http.Header{},
+		// Captured now since the key may be released after the request has
+		// finished, when the context must no longer be used.
+		bg:     context.WithoutCancel(ctx.Context()),
+		logger: RequestLogger(api, ctx),
+	}
+}
+
+// idempotentContext records the response written by the operation so it can
+// be saved for its idempotency key.
+type idempotentContext struct {
+	ContextWrapper
+	store   IdempotencyStore
+	key     string
+	status  int
+	headers http.Header
+	body    bytes.Buffer
+	bg      context.Context
+	logger  *slog.Logger
+}
+
+func (c *idempotentContext) SetStatus(code int) {
+	c.status = code
+	c.Unwrap().SetStatus(code)
+}
+
+func (c *idempotentContext) SetHeader(name, value string) {
+	c.headers.Set(name, value)
+	c.Unwrap().SetHeader(name, value)
+}
+
+func (c *idempotentContext) AppendHeader(name, value string) {
+	c.headers.Add(name, value)
+	c.Unwrap().AppendHeader(name, value)
+}
+
+func (c *idempotentContext) BodyWriter() io.Writer {
+	return io.MultiWriter(c.Unwrap().BodyWriter(), &c.body)
+}
+
+// finish saves the recorded response. Server errors are not saved so that
+// the client can retry the request.
+func (c *idempotentContext) finish() {
+	var resp *IdempotentResponse
+	if c.status != 0 && c.status < http.StatusInternalServerError {
+		resp = &IdempotentResponse{
+			Status:  c.status,
+			Headers: c.headers,
+			Body:    c.body.Bytes(),
+		}
+	}
+	c.release(resp)
+}
+
+// release finishes the key with the response, logging any error since the
+// response has already been written.
+func (c *idempotentContext) release(resp *IdempotentResponse) {
+	if err := c.store.Finish(c.bg, c.key, resp); err != nil {
+		c.logger.Error("unable to save idempotency key", "error", err)
+	}
+}
+
+// releaseAfter releases the key once the channel is closed, e.g. when a
+// handler abandoned after a timeout returns. Until then retries are rejected
+// as in progress rather than running the handler a second time.
+func (c *idempotentContext) releaseAfter(done <-chan struct{}) {
+	go func() {
+		<-done
+		c.release(nil)
+	}()
+}
+
+// documentIdempotency adds the `Idempotency-Key` header parameter and its
+// error responses to an operation.
+func documentIdempotency(op *Operation) {
+	found := false
+	for _, p := range op.Parameters {
+		if p.In == "header" && strings.EqualFold(p.Name, HeaderIdempotencyKey) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		maxLength := MaxIdempotencyKeyLength
+		op.Parameters = append(op.Parameters, &Param{
+			Name:        HeaderIdempotencyKey,
+			In:          "header",
+			Description: "Unique key to safely retry the request. Retries with the same key and body return the original response.",
+			Required:    op.IdempotencyKeyRequired,
+			Schema:      &Schema{Type: TypeString, MaxLength: &maxLength},
+		})
+	}
+
+	for _, status := range []int{http.StatusBadRequest, http.StatusConflict, http.StatusUnprocessableEntity} {
+		if !slices.Contains(op.Errors, status) {
+			op.Errors = append(op.Errors, status)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package huma
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type PaymentInput struct {
+	Body struct {
+		Amount int `json:"amount"`
+	}
+}
+
+type PaymentOutput struct {
+	Status   int
+	Location string `header:"Location"`
+	Body     struct {
+		ID     int `json:"id"`
+		Amount int `json:"amount"`
+	}
+}
+
+func newIdempotencyAPI(op Operation, handler func(context.Context, *PaymentInput) (*PaymentOutput, error)) http.Handler {
+	config := DefaultConfig("Test API", "1.0.0")
+	config.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	r := chi.NewRouter()
+	api := NewTestAdapter(r, config)
+	Register(api, op, handler)
+	return r
+}
+
+func pay(handler http.Handler, key, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodPost, "/payments", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	calls := 0
+	r := newIdempotencyAPI(Operation{
+		Method:         http.MethodPost,
+		Path:           "/payments",
+		IdempotencyKey: true,
+	}, func(ctx context.Context, input *PaymentInput) (*PaymentOutput, error) {
+		calls++
+		if input.Body.Amount < 0 {
+			return nil, Error400BadRequest("invalid amount")
+		}
+		if input.Body.Amount == 0 {
+			return nil, Error500InternalServerError("payment processor unavailable")
+		}
+		resp := &PaymentOutput{Status: http.StatusCreated, Location: "/payments/1"}
+		resp.Body.ID = calls
+		resp.Body.Amount = input.Body.Amount
+		return resp, nil
+	})
+
+	w := pay(r, "abc", `{"amount": 100}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/payments/1", w.Header().Get("Location"))
+	assert.Empty(t, w.Header().Get(HeaderIdempotentReplayed))
+	body := w.Body.String()
+
+	// Retries return the stored response without calling the handler.
+	w = pay(r, "abc", `{"amount": 100}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/payments/1", w.Header().Get("Location"))
+	assert.Equal(t, "true", w.Header().Get(HeaderIdempotentReplayed))
+	assert.Equal(t, body, w.Body.String())
+	assert.Equal(t, 1, calls)
+
+	// Reusing the key for a different request is an error.
+	w = pay(r, "abc", `{"amount": 200}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "different request")
+	assert.Equal(t, 1, calls)
+
+	// Client errors are stored too.
+	assert.Equal(t, http.StatusBadRequest, pay(r, "neg", `{"amount": -1}`).Code)
+	assert.Equal(t, http.StatusBadRequest, pay(r, "neg", `{"amount": -1}`).Code)
+	assert.Equal(t, 2, calls)
+
+	// Server errors are not stored so they can be retried.
+	assert.Equal(t, http.StatusInternalServerError, pay(r, "zero", `{"amount": 0}`).Code)
+	assert.Equal(t, http.StatusInternalServerError, pay(r, "zero", `{"amount": 0}`).Code)
+	assert.Equal(t, 4, calls)
+
+	// Requests without a key always run.
+	pay(r, "", `{"amount": 100}`)
+	pay(r, "", `{"amount": 100}`)
+	assert.Equal(t, 6, calls)
+
+	w = pay(r, strings.Repeat("a", MaxIdempotencyKeyLength+1), `{"amount": 100}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, 6, calls)
+}
+
+func TestIdempotencyKeyRequired(t *testing.T) {
+	r := newIdempotencyAPI(Operation{
+		Method:                 http.MethodPost,
+		Path:                   "/payments",
+		IdempotencyKeyRequired: true,
+	}, func(ctx context.Context, input *PaymentInput) (*PaymentOutput, error) {
+		return &PaymentOutput{Status: http.StatusOK}, nil
+	})
+
+	w := pay(r, "", `{"amount": 100}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Idempotency-Key header is required")
+
+	assert.Equal(t, http.StatusOK, pay(r, "abc", `{"amount": 100}`).Code)
+}
+
+func TestIdempotencyConcurrent(t *testing.T) {
+	once := sync.Once{}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	r := newIdempotencyAPI(Operation{
+		Method:         http.MethodPost,
+		Path:           "/payments",
+		IdempotencyKey: true,
+	}, func(ctx context.Context, input *PaymentInput) (*PaymentOutput, error) {
+		once.Do(func() { close(started) })
+		<-release
+		return &PaymentOutput{Status: http.StatusOK}, nil
+	})
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assert.Equal(t, http.StatusOK, pay(r, "abc", `{"amount": 100}`).Code)
+	}()
+
+	<-started
+	w := pay(r, "abc", `{"amount": 100}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "in progress")
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, http.StatusOK, pay(r, "abc", `{"amount": 100}`).Code)
+}
+
+func TestIdempotencyPanic(t *testing.T) {
+	calls := 0
+	r := newIdempotencyAPI(Operation{
+		Method:         http.MethodPost,
+		Path:           "/payments",
+		IdempotencyKey: true,
+	}, func(ctx context.Context, input *PaymentInput) (*PaymentOutput, error) {
+		calls++
+		if calls == 1 {
+			panic("boom")
+		}
+		return &PaymentOutput{Status: http.StatusOK}, nil
+	})
+
+	// The key is released so the request can be retried.
+	assert.Equal(t, http.StatusInternalServerError, pay(r, "abc", `{"amount": 100}`).Code)
+	assert.Equal(t, http.StatusOK, pay(r, "abc", `{"amount": 100}`).Code)
+}
+
+func TestIdempotencyTimeout(t *testing.T) {
+	release := make(chan struct{})
+	calls := 0
+	r := newIdempotencyAPI(Operation{
+		Method:         http.MethodPost,
+		Path:           "/payments",
+		IdempotencyKey: true,
+		Timeout:        10 * time.Millisecond,
+	}, func(ctx context.Context, input *PaymentInput) (*PaymentOutput, error) {
+		calls++
+		if calls == 1 {
+			// Ignores the timeout and keeps going after the response is sent.
+			<-release
+		}
+		return &PaymentOutput{Status: http.StatusOK}, nil
+	})
+
+	assert.Equal(t, http.StatusServiceUnavailable, pay(r, "abc", `{"amount": 100}`).Code)
+
+	// The abandoned handler may still apply its side effects, so the key stays
+	// reserved until it returns.
+	w := pay(r, "abc", `{"amount": 100}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "in progress")
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		return pay(r, "abc", `{"amount": 100}`).Code == http.StatusOK
+	}, time.Second, time.Millisecond)
+}
+
+func TestIdempotencyScope(t *testing.T) {
+	config := DefaultConfig("Test API", "1.0.0")
+	config.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	config.IdempotencyScope = func(ctx Context) string {
+		return ctx.Header("X-Client")
+	}
+	r := chi.NewRouter()
+	api := NewTestAdapter(r, config)
+	calls := 0
+	Register(api, Operation{
+		Method:         http.MethodPost,
+		Path:           "/payments",
+		IdempotencyKey: true,
+	}, func(ctx context.Context, input *PaymentInput) (*PaymentOutput, error) {
+		calls++
+		resp := &PaymentOutput{Status: http.StatusCreated}
+		resp.Body.ID = calls
+		return resp, nil
+	})
+
+	payAs := func(client string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, "/payments", strings.NewReader(`{"amount": 100}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", "abc")
+		req.Header.Set("X-Client", client)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := payAs("alice")
+	assert.Equal(t, http.StatusCreated, first.Code)
+
+	// Another client using the same key gets its own response.
+	other := payAs("bob")
+	assert.Equal(t, http.StatusCreated, other.Code)
+	assert.Empty(t, other.Header().Get(HeaderIdempotentReplayed))
+	assert.NotEqual(t, first.Body.String(), other.Body.String())
+
+	// The same client gets its saved response.
+	replay := payAs("alice")
+	assert.Equal(t, "true", replay.Header().Get(HeaderIdempotentReplayed))
+	assert.Equal(t, first.Body.String(), replay.Body.String())
+	assert.Equal(t, 2, calls)
+}
+
+type failingIdempotencyStore struct{}
+
+func (failingIdempotencyStore) Start(ctx context.Context, key, fingerprint string) (*IdempotentResponse, error) {
+	return nil, errors.New("store unavailable")
+}
+
+func (failingIdempotencyStore) Finish(ctx context.Context, key string, resp *IdempotentResponse) error {
+	return nil
+}
+
+func TestIdempotencyStoreError(t *testing.T) {
+	config := DefaultConfig("Test API", "1.0.0")
+	config.IdempotencyStore = failingIdempotencyStore{}
+	config.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	r := chi.NewRouter()
+	api := NewTestAdapter(r, config)
+	Register(api, Operation{
+		Method:         http.MethodPost,
+		Path:           "/payments",
+		IdempotencyKey: true,
+	}, func(ctx context.Context, input *PaymentInput) (*PaymentOutput, error) {
+		t.Fatal("handler should not be called")
+		return nil, nil
+	})
+
+	// Fails closed since running the request could apply it twice.
+	assert.Equal(t, http.StatusInternalServerError, pay(r, "abc", `{"amount": 100}`).Code)
+}
+
+func TestIdempotencyOpenAPI(t *testing.T) {
+	r := chi.NewRouter()
+	api := NewTestAdapter(r, DefaultConfig("Test API", "1.0.0"))
+	Register(api, Operation{
+		Method:                 http.MethodPost,
+		Path:                   "/payments",
+		IdempotencyKeyRequired: true,
+	}, func(ctx context.Context, input *PaymentInput) (*PaymentOutput, error) {
+		return &PaymentOutput{Status: http.StatusOK}, nil
+	})
+
+	op := api.OpenAPI().Paths["/payments"].Post
+	var param *Param
+	for _, p := range op.Parameters {
+		if p.Name == HeaderIdempotencyKey {
+			param = p
+		}
+	}
+	require.NotNil(t, param)
+	assert.Equal(t, "header", param.In)
+	assert.True(t, param.Required)
+	assert.Contains(t, op.Responses, "400")
+	assert.Contains(t, op.Responses, "409")
+	assert.Contains(t, op.Responses, "422")
+
+	assert.Panics(t, func() {
+		Register(api, Operation{
+			Method:         http.MethodGet,
+			Path:           "/stream",
+			IdempotencyKey: true,
+		}, func(ctx context.Context, input *struct{}) (*struct{ Body func(Context) }, error) {
+			return nil, nil
+		})
+	})
+}
+
+func TestMemoryIdempotencyStoreExpiry(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	s := NewMemoryIdempotencyStore(time.Hour)
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, err := s.Start(ctx, "a", "1")
+	require.NoError(t, err)
+	require.NoError(t, s.Finish(ctx, "a", &IdempotentResponse{Status: http.StatusCreated}))
+
+	resp, err := s.Start(ctx, "a", "1")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.Status)
+
+	// Expired keys can be reused, even for a different request.
+	now = now.Add(2 * time.Hour)
+	resp, err = s.Start(ctx, "a", "2")
+	require.NoError(t, err)
+	assert.Nil(t, resp)
+	assert.Len(t, s.entries, 1)
+}
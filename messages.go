@@ -0,0 +1,139 @@
+package huma
+
+import (
+	"github.com/danielgtaylor/huma/v2/negotiation"
+)
+
+// Validation & error message format strings. These are the default English
+// messages and are also used as the keys for translations in a
+// `MessageCatalog`. Translations must use the same `fmt` verbs in the same
+// order as the originals.
+const (
+	MsgExpectedBoolean          = "expected boolean"
+	MsgExpectedNumber           = "expected number"
+	MsgExpectedString           = "expected string"
+	MsgExpectedArray            = "expected array"
+	MsgExpectedObject           = "expected object"
+	MsgExpectedOneOf            = "expected value to be one of \"%s\""
+	MsgExpectedMinimum          = "expected number >= %v"
+	MsgExpectedExclusiveMinimum = "expected number > %v"
+	MsgExpectedMaximum          = "expected number <= %v"
+	MsgExpectedExclusiveMaximum = "expected number < %v"
+	MsgExpectedMultipleOf       = "expected number to be a multiple of %v"
+	MsgExpectedMinLength        = "expected length >= %d"
+	MsgExpectedMaxLength        = "expected length <= %d"
+	MsgExpectedPattern          = "expected string to match pattern %s"
+	MsgExpectedMinItems         = "expected array length >= %d"
+	MsgExpectedMaxItems         = "expected array length <= %d"
+	MsgExpectedUniqueItems      = "expected array items to be unique"
+	MsgExpectedMinProperties    = "expected object with at least %d properties"
+	MsgExpectedMaxProperties    = "expected object with at most %d properties"
+	MsgExpectedRequired         = "expected required property %s to be present"
+	MsgExpectedBase64           = "expected string to be base64 encoded"
+	MsgUnexpectedProperty       = "unexpected property"
+	MsgWriteOnlyNonZero         = "write only property is non-zero"
+
+	MsgExpectedRFC3339DateTime = "expected string to be RFC 3339 date-time"
+	MsgExpectedRFC1123DateTime = "expected string to be RFC 1123 date-time"
+	MsgExpectedRFC3339Date     = "expected string to be RFC 3339 date"
+	MsgExpectedRFC3339Time     = "expected string to be RFC 3339 time"
+	MsgExpectedRFC5322Email    = "expected string to be RFC 5322 email: %v"
+	MsgExpectedRFC5890Hostname = "expected string to be RFC 5890 hostname"
+	MsgExpectedRFC5890IDN      = "expected string to be RFC 5890 hostname: %v"
+	MsgExpectedRFC2673IPv4     = "expected string to be RFC 2673 ipv4"
+	MsgExpectedRFC2373IPv6     = "expected string to be RFC 2373 ipv6"
+	MsgExpectedRFC3986URI      = "expected string to be RFC 3986 uri: %v"
+	MsgExpectedRFC4122UUID     = "expected string to be RFC 4122 uuid: %v"
+	MsgExpectedRFC6570Template = "expected string to be RFC 6570 uri-template"
+	MsgExpectedRFC6901Pointer  = "expected string to be RFC 6901 json-pointer"
+	MsgExpectedRFC6901RelPtr   = "expected string to be RFC 6901 relative-json-pointer"
+	MsgExpectedRegex           = "expected string to be regex: %v"
+
+	MsgRequiredPathParam = "required path parameter is missing"
+	MsgInvalidInteger    = "invalid integer"
+	MsgInvalidFloat      = "invalid float"
+	MsgInvalidBoolean    = "invalid boolean"
+	MsgInvalidDateTime   = "invalid date/time for format %s"
+	MsgValidationFailed  = "validation failed"
+
+	MsgRequestBodyRequired = "request body is required"
+	MsgRequestBodyTooLarge = "request body is too large limit=%d bytes"
+	MsgRequestBodyTimeout  = "request body read timeout"
+	MsgRequestBodyRead     = "cannot read request body"
+)
+
+// MessageCatalog translates validation & error messages into a specific
+// locale. Messages are keyed by their default English format strings (see the
+// `Msg...` constants) and any message without a translation falls back to
+// English.
+//
+//	huma.RegisterMessageCatalog(&huma.MessageCatalog{
+//		Locale: "de",
+//		Messages: map[string]string{
+//			huma.MsgExpectedBoolean: "Boolescher Wert erwartet",
+//			huma.MsgExpectedMinimum: "Zahl >= %v erwartet",
+//		},
+//	})
+type MessageCatalog struct {
+	// Locale is the BCP 47 language tag for this catalog, e.g. `de` or `ja-JP`.
+	Locale string
+
+	// Messages maps default English message format strings to their
+	// translations for this locale.
+	Messages map[string]string
+}
+
+// Get returns the translated message for the given default message, or the
+// default message itself if no translation is available. It is safe to call
+// on a `nil` catalog.
+func (c *MessageCatalog) Get(msg string) string {
+	if c != nil {
+		if translated, ok := c.Messages[msg]; ok {
+			return translated
+		}
+	}
+	return msg
+}
+
+var (
+	catalogs       = map[string]*MessageCatalog{}
+	catalogLocales = []string{}
+)
+
+// RegisterMessageCatalog registers a message catalog for its locale, making
+// it available for selection via the `Accept-Language` request header. Since
+// validation messages are precomputed when schemas are created, catalogs must
+// be registered at service startup *before* any schemas are created or
+// operations are registered. It is not safe for concurrent use.
+func RegisterMessageCatalog(c *MessageCatalog) {
+	if _, ok := catalogs[c.Locale]; !ok {
+		catalogLocales = append(catalogLocales, c.Locale)
+	}
+	catalogs[c.Locale] = c
+}
+
+// GetMessageCatalog returns the registered catalog for a locale, or `nil`
+// if there is no such catalog, in which case default messages are used.
+func GetMessageCatalog(locale string) *MessageCatalog {
+	return catalogs[locale]
+}
+
+// SelectLocale returns the best registered message catalog locale given an
+// `Accept-Language` header value, or an empty string if none match and the
+// default messages should be used. RFC 4647 matching is used, so e.g. a
+// request for `de-CH` will select a `de` catalog.
+func SelectLocale(acceptLanguage string) string {
+	if acceptLanguage == "" || len(catalogLocales) == 0 {
+		return ""
+	}
+	return negotiation.SelectLanguage(acceptLanguage, catalogLocales)
+}
+
+// Msg returns the translated message for the given locale, falling back to
+// the default English message.
+func Msg(locale, msg string) string {
+	if locale == "" {
+		return msg
+	}
+	return catalogs[locale].Get(msg)
+}
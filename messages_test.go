@@ -0,0 +1,201 @@
+package huma
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// withCatalogs registers the given catalogs for the duration of a test.
+func withCatalogs(t *testing.T, cats ...*MessageCatalog) {
+	origCatalogs, origLocales := catalogs, catalogLocales
+	catalogs, catalogLocales = map[string]*MessageCatalog{}, []string{}
+	for _, c := range cats {
+		RegisterMessageCatalog(c)
+	}
+	t.Cleanup(func() {
+		catalogs, catalogLocales = origCatalogs, origLocales
+	})
+}
+
+var catalogDE = &MessageCatalog{
+	Locale: "de",
+	Messages: map[string]string{
+		MsgExpectedBoolean:     "Boolescher Wert erwartet",
+		MsgExpectedMinimum:     "Zahl >= %v erwartet",
+		MsgExpectedRequired:    "Pflichtfeld %s fehlt",
+		MsgExpectedRFC4122UUID: "RFC 4122 UUID erwartet: %v",
+		MsgInvalidInteger:      "ungültige Ganzzahl",
+		MsgValidationFailed:    "Validierung fehlgeschlagen",
+	},
+}
+
+var catalogJA = &MessageCatalog{
+	Locale: "ja",
+	Messages: map[string]string{
+		MsgExpectedMinimum: "%v 以上の数値が必要です",
+	},
+}
+
+func TestMessageCatalog(t *testing.T) {
+	withCatalogs(t, catalogDE, catalogJA)
+
+	var nilCatalog *MessageCatalog
+	assert.Equal(t, MsgExpectedArray, nilCatalog.Get(MsgExpectedArray))
+	assert.Equal(t, MsgExpectedArray, catalogDE.Get(MsgExpectedArray))
+	assert.Same(t, catalogDE, GetMessageCatalog("de"))
+
+	assert.Equal(t, "", SelectLocale(""))
+	assert.Equal(t, "", SelectLocale("fr"))
+	assert.Equal(t, "ja", SelectLocale("de;q=0.5, ja"))
+	assert.Equal(t, "de", SelectLocale("de"))
+
+	type Model struct {
+		ID    string `json:"id" format:"uuid"`
+		Count int    `json:"count" minimum:"5"`
+		OK    bool   `json:"ok"`
+	}
+
+	registry := NewMapRegistry("#/components/schemas/", DefaultSchemaNamer)
+	s := registry.Schema(reflect.TypeOf(Model{}), false, "Model")
+	pb := NewPathBuffer([]byte{}, 0)
+
+	for _, item := range []struct {
+		locale   string
+		expected []string
+	}{
+		{"", []string{"expected required property ok to be present", "expected string to be RFC 4122 uuid: invalid UUID length: 3", "expected number >= 5"}},
+		{"de", []string{"Pflichtfeld ok fehlt", "RFC 4122 UUID erwartet: invalid UUID length: 3", "Zahl >= 5 erwartet"}},
+		{"ja", []string{"expected required property ok to be present", "5 以上の数値が必要です"}},
+	} {
+		t.Run(item.locale, func(t *testing.T) {
+			res := &ValidateResult{Locale: item.locale}
+			pb.Reset()
+			Validate(registry, s, pb, ModeWriteToServer, map[string]any{"id": "bad", "count": 1.0}, res)
+			msgs := mapTo(res.Errors, func(e error) string {
+				return e.(*ErrorDetail).Message
+			})
+			for _, expected := range item.expected {
+				assert.Contains(t, msgs, expected)
+			}
+		})
+	}
+}
+
+func TestLocalizedRequest(t *testing.T) {
+	withCatalogs(t, catalogDE)
+
+	r := chi.NewRouter()
+	api := NewTestAdapter(r, DefaultConfig("Test API", "1.0.0"))
+
+	Register(api, Operation{
+		Method: http.MethodPut,
+		Path:   "/things/{id}",
+	}, func(ctx context.Context, input *struct {
+		ID   int `path:"id"`
+		Body struct {
+			Enabled bool `json:"enabled"`
+		}
+	}) (*struct{}, error) {
+		return nil, nil
+	})
+
+	for _, item := range []struct {
+		lang     string
+		detail   string
+		messages []string
+	}{
+		{"", "validation failed", []string{"invalid integer", "expected boolean"}},
+		{"de-DE, fr;q=0.5", "Validierung fehlgeschlagen", []string{"ungültige Ganzzahl", "Boolescher Wert erwartet"}},
+	} {
+		req, _ := http.NewRequest(http.MethodPut, "/things/abc", strings.NewReader(`{"enabled": 1}`))
+		req.Header.Set("Content-Type", "application/json")
+		if item.lang != "" {
+			req.Header.Set("Accept-Language", item.lang)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+		var model ErrorModel
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &model))
+		assert.Equal(t, item.detail, model.Detail)
+		msgs := mapTo(model.Errors, func(e *ErrorDetail) string {
+			return e.Message
+		})
+		assert.ElementsMatch(t, item.messages, msgs)
+	}
+}
+
+func TestMessageCatalogTranslatedOnce(t *testing.T) {
+	// The translated enum message happens to match another message key, which
+	// must not be translated a second time.
+	withCatalogs(t, &MessageCatalog{
+		Locale: "zz",
+		Messages: map[string]string{
+			MsgExpectedOneOf:  "invalid %s",
+			MsgInvalidBoolean: "wrong",
+		},
+	})
+
+	registry := NewMapRegistry("#/components/schemas/", DefaultSchemaNamer)
+	s := registry.Schema(reflect.TypeOf(struct {
+		Kind string `json:"kind" enum:"boolean"`
+	}{}), false, "Model")
+	res := &ValidateResult{Locale: "zz"}
+	Validate(registry, s, NewPathBuffer([]byte{}, 0), ModeWriteToServer, map[string]any{"kind": "other"}, res)
+	assert.Len(t, res.Errors, 1)
+	assert.Equal(t, "invalid boolean", res.Errors[0].(*ErrorDetail).Message)
+}
+
+func TestLocalizedBodyErrors(t *testing.T) {
+	withCatalogs(t, &MessageCatalog{
+		Locale: "de",
+		Messages: map[string]string{
+			MsgRequestBodyRequired: "Anfragetext erforderlich",
+			MsgRequestBodyTooLarge: "Anfragetext zu groß, Limit=%d Bytes",
+		},
+	})
+
+	r := chi.NewRouter()
+	api := NewTestAdapter(r, DefaultConfig("Test API", "1.0.0"))
+
+	Register(api, Operation{
+		Method:       http.MethodPut,
+		Path:         "/things",
+		MaxBodyBytes: 10,
+	}, func(ctx context.Context, input *struct {
+		Body struct {
+			Name string `json:"name"`
+		}
+	}) (*struct{}, error) {
+		return nil, nil
+	})
+
+	for _, item := range []struct {
+		body   string
+		status int
+		detail string
+	}{
+		{"", http.StatusBadRequest, "Anfragetext erforderlich"},
+		{`{"name": "too long"}`, http.StatusRequestEntityTooLarge, "Anfragetext zu groß, Limit=10 Bytes"},
+	} {
+		req, _ := http.NewRequest(http.MethodPut, "/things", strings.NewReader(item.body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept-Language", "de")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, item.status, w.Code)
+
+		var model ErrorModel
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &model))
+		assert.Equal(t, item.detail, model.Detail)
+	}
+}
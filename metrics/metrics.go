@@ -0,0 +1,173 @@
+// Package metrics provides Prometheus metrics for operations registered via
+// `huma.Register`. Metrics are labeled by operation ID and path template
+// rather than the raw request path to keep cardinality low, and are served
+// on a configurable path which is hidden from the OpenAPI document.
+//
+//	api.UseMiddleware(metrics.New(api, metrics.Options{}))
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+// Options configures the metrics middleware and endpoint.
+type Options struct {
+	// Path to serve metrics on. Defaults to `/metrics`.
+	Path string
+
+	// Namespace is prepended to each metric name. Defaults to `huma`.
+	Namespace string
+
+	// Buckets are the request duration histogram buckets in seconds. Defaults
+	// to `prometheus.DefBuckets`.
+	Buckets []float64
+
+	// Registry to register metrics with and gather them from. Defaults to the
+	// global Prometheus registry, which includes Go runtime & process metrics.
+	Registry *prometheus.Registry
+}
+
+// labels are the labels applied to every metric.
+var labels = []string{"operation", "method", "path"}
+
+// collectors are the metrics recorded for each request.
+type collectors struct {
+	requests         *prometheus.CounterVec
+	duration         *prometheus.HistogramVec
+	inFlight         *prometheus.GaugeVec
+	validationErrors *prometheus.CounterVec
+}
+
+func newCollectors(opts Options) *collectors {
+	return &collectors{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: opts.Namespace,
+			Name:      "requests_total",
+			Help:      "Total number of requests by operation and status code.",
+		}, append(labels, "code")),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: opts.Namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Request duration in seconds by operation.",
+			Buckets:   opts.Buckets,
+		}, labels),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: opts.Namespace,
+			Name:      "requests_in_flight",
+			Help:      "Number of requests currently being handled by operation.",
+		}, labels),
+		validationErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: opts.Namespace,
+			Name:      "validation_errors_total",
+			Help:      "Total number of request validation errors by operation.",
+		}, labels),
+	}
+}
+
+// New registers the metrics endpoint on the API's adapter and returns a
+// middleware which records request counts, durations, in-flight requests,
+// and validation errors for each operation. Responses are labeled with their
+// status code, so e.g. `code=~"5.."` selects server errors. It panics if the
+// metrics cannot be registered, e.g. when called twice with the same
+// registry.
+func New(api huma.API, opts Options) func(ctx huma.Context, next func(huma.Context)) {
+	if opts.Path == "" {
+		opts.Path = "/metrics"
+	}
+	if opts.Namespace == "" {
+		opts.Namespace = "huma"
+	}
+	if opts.Buckets == nil {
+		opts.Buckets = prometheus.DefBuckets
+	}
+
+	var registerer prometheus.Registerer = prometheus.DefaultRegisterer
+	var gatherer prometheus.Gatherer = prometheus.DefaultGatherer
+	if opts.Registry != nil {
+		registerer = opts.Registry
+		gatherer = opts.Registry
+	}
+
+	c := newCollectors(opts)
+	registerer.MustRegister(c.requests, c.duration, c.inFlight, c.validationErrors)
+
+	// Registered directly on the adapter so it is not part of the OpenAPI and
+	// is not itself measured.
+	api.Adapter().Handle(&huma.Operation{
+		Method: http.MethodGet,
+		Path:   opts.Path,
+	}, func(ctx huma.Context) {
+		mfs, err := gatherer.Gather()
+		if err != nil {
+			huma.RequestLogger(api, ctx).Error("unable to gather metrics", "error", err)
+			if len(mfs) == 0 {
+				huma.WriteErr(api, ctx, http.StatusInternalServerError, "unable to gather metrics", err)
+				return
+			}
+		}
+		format := expfmt.Negotiate(http.Header{"Accept": []string{ctx.Header("Accept")}})
+		ctx.SetHeader("Content-Type", string(format))
+		enc := expfmt.NewEncoder(ctx.BodyWriter(), format)
+		for _, mf := range mfs {
+			if err := enc.Encode(mf); err != nil {
+				huma.RequestLogger(api, ctx).Error("unable to encode metrics", "error", err)
+				return
+			}
+		}
+	})
+
+	return func(ctx huma.Context, next func(huma.Context)) {
+		start := time.Now()
+		op := ctx.Operation()
+		values := []string{op.OperationID, op.Method, op.Path}
+
+		inFlight := c.inFlight.WithLabelValues(values...)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		mc := &metricsContext{ContextWrapper: huma.WrapContext(ctx)}
+		mc.ctx = huma.WithPhaseHook(ctx.Context(), func(_ huma.Context, phase huma.Phase) func(error) {
+			return func(err error) {
+				if err == nil || phase != huma.PhaseValidate {
+					return
+				}
+				if u, ok := err.(interface{ Unwrap() []error }); ok {
+					c.validationErrors.WithLabelValues(values...).Add(float64(len(u.Unwrap())))
+				}
+			}
+		})
+
+		next(mc)
+
+		status := mc.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		c.duration.WithLabelValues(values...).Observe(time.Since(start).Seconds())
+		c.requests.WithLabelValues(append(values, strconv.Itoa(status))...).Inc()
+	}
+}
+
+// metricsContext wraps a `huma.Context` to record the response status and
+// observe request phases.
+type metricsContext struct {
+	huma.ContextWrapper
+	ctx    context.Context
+	status int
+}
+
+func (c *metricsContext) Context() context.Context {
+	return c.ctx
+}
+
+func (c *metricsContext) SetStatus(code int) {
+	c.status = code
+	c.Unwrap().SetStatus(code)
+}
@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+type ThingInput struct {
+	ID string `path:"id"`
+}
+
+type CreateThingInput struct {
+	Body struct {
+		Name string `json:"name" minLength:"3"`
+		Size int    `json:"size" minimum:"1"`
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	_, api := humatest.New(t)
+	registry := prometheus.NewRegistry()
+	api.UseMiddleware(New(api, Options{Registry: registry}))
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-thing",
+		Method:      http.MethodGet,
+		Path:        "/things/{id}",
+	}, func(ctx context.Context, input *ThingInput) (*struct{}, error) {
+		switch input.ID {
+		case "missing":
+			return nil, huma.Error404NotFound("not found")
+		case "broken":
+			return nil, huma.Error500InternalServerError("broken")
+		}
+		return nil, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "create-thing",
+		Method:      http.MethodPost,
+		Path:        "/things",
+	}, func(ctx context.Context, input *CreateThingInput) (*struct{}, error) {
+		return nil, nil
+	})
+
+	api.Get("/things/1")
+	api.Get("/things/2")
+	api.Get("/things/missing")
+	api.Get("/things/broken")
+	api.Post("/things", strings.NewReader(`{"name": "a", "size": 0}`))
+
+	resp := api.Get("/metrics")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Header().Get("Content-Type"), "text/plain")
+
+	body := resp.Body.String()
+	for _, line := range []string{
+		`huma_requests_total{code="204",method="GET",operation="get-thing",path="/things/{id}"} 2`,
+		`huma_requests_total{code="404",method="GET",operation="get-thing",path="/things/{id}"} 1`,
+		`huma_requests_total{code="500",method="GET",operation="get-thing",path="/things/{id}"} 1`,
+		`huma_requests_total{code="422",method="POST",operation="create-thing",path="/things"} 1`,
+		`huma_request_duration_seconds_count{method="GET",operation="get-thing",path="/things/{id}"} 4`,
+		`huma_requests_in_flight{method="GET",operation="get-thing",path="/things/{id}"} 0`,
+		`huma_validation_errors_total{method="POST",operation="create-thing",path="/things"} 2`,
+	} {
+		assert.Contains(t, body, line)
+	}
+
+	// Raw paths are never used as labels.
+	assert.NotContains(t, body, "/things/missing")
+
+	// The metrics endpoint is hidden from the OpenAPI and not measured.
+	assert.Nil(t, api.OpenAPI().Paths["/metrics"])
+	assert.NotContains(t, body, `path="/metrics"`)
+}
+
+func TestMetricsOptions(t *testing.T) {
+	_, api := humatest.New(t)
+	registry := prometheus.NewRegistry()
+	api.UseMiddleware(New(api, Options{
+		Path:      "/internal/metrics",
+		Namespace: "myapp",
+		Buckets:   []float64{0.1, 1},
+		Registry:  registry,
+	}))
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-thing",
+		Method:      http.MethodGet,
+		Path:        "/things/{id}",
+	}, func(ctx context.Context, input *ThingInput) (*struct{}, error) {
+		return nil, nil
+	})
+
+	api.Get("/things/1")
+
+	resp := api.Get("/internal/metrics")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Body.String(), `myapp_request_duration_seconds_bucket{method="GET",operation="get-thing",path="/things/{id}",le="0.1"} 1`)
+
+	// Registering twice with the same registry is a startup error.
+	assert.Panics(t, func() {
+		New(api, Options{Path: "/other", Namespace: "myapp", Registry: registry})
+	})
+}
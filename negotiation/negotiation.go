@@ -1,3 +1,6 @@
+// Package negotiation provides utilities for HTTP content negotiation, such
+// as selecting a response content type from the `Accept` header, a language
+// from `Accept-Language`, or a content coding from `Accept-Encoding`.
 package negotiation
 
 import (
@@ -49,68 +52,280 @@ func SelectQValue(header string, allowed []string) string {
 	return best
 }
 
+// nextValue parses the next comma-separated entry from a header with optional
+// quality values, starting at `pos`. It returns the entry's name (without any
+// parameters), its quality value (defaulting to 1), and the position of the
+// next entry. Parameters other than `q` are ignored. When there are no more
+// entries, `next` will be -1. This does not allocate.
+func nextValue(header string, pos int) (name string, q float64, next int) {
+	end := strings.IndexByte(header[pos:], ',')
+	if end == -1 {
+		end = len(header)
+		next = -1
+	} else {
+		end += pos
+		next = end + 1
+	}
+	entry := header[pos:end]
+
+	q = 1.0
+	semi := strings.IndexByte(entry, ';')
+	if semi == -1 {
+		return strings.Trim(entry, " \t"), q, next
+	}
+	name = strings.Trim(entry[:semi], " \t")
+
+	params := entry[semi+1:]
+	for params != "" {
+		param := params
+		if i := strings.IndexByte(params, ';'); i != -1 {
+			param, params = params[:i], params[i+1:]
+		} else {
+			params = ""
+		}
+		param = strings.Trim(param, " \t")
+		if len(param) > 2 && (param[0] == 'q' || param[0] == 'Q') && param[1] == '=' {
+			if parsed, err := strconv.ParseFloat(param[2:], 64); err == nil {
+				q = parsed
+			}
+		}
+	}
+	return name, q, next
+}
+
 // SelectQValueFast is a faster version of SelectQValue that does not
 // need any dynamic memory allocations.
 func SelectQValueFast(header string, allowed []string) string {
 	best := ""
 	bestQ := 0.0
 
-	name := ""
-	start := -1
-	end := 0
+	for pos := 0; pos != -1 && header != ""; {
+		var name string
+		var q float64
+		name, q, pos = nextValue(header, pos)
 
-	for pos, char := range header {
-		// Format is like "a; q=0.5, b;q=1.0,c; q=0.3"
-		if char == ';' {
-			name = header[start : end+1]
-			start = -1
+		if q <= 0 {
 			continue
 		}
 
-		if char == ',' || pos == len(header)-1 {
-			q := 1.0
-			if pos == len(header)-1 {
-				// This is the end, so it must be the name.
-				// Example: "application/yaml"
-				name = header[start : pos+1]
-			} else if name == "" {
-				// No name yet means we did not encounter a `;`.
-				// Example: "a, b, c"
-				name = header[start:pos]
-			} else {
-				if parsed, _ := strconv.ParseFloat(header[start+2:end+1], 64); parsed > 0 {
-					q = parsed
-				}
+		found := false
+		for _, n := range allowed {
+			if n == name {
+				found = true
+				break
 			}
-			start = -1
+		}
+
+		if !found {
+			// Skip formats we don't support.
+			continue
+		}
+
+		if q > bestQ || (q == bestQ && name == allowed[0]) {
+			bestQ = q
+			best = name
+		}
+	}
+
+	return best
+}
+
+// SelectMediaType selects the best media type from the allowed set given an
+// `Accept` header. Media ranges like `application/*` and `*/*` are supported,
+// with more specific ranges taking precedence when determining the quality
+// of an allowed type. Allowed entries without a `/`, like `json`, are treated
+// as structured syntax suffixes and match any requested concrete type ending
+// in that suffix, e.g. `application/vnd.foo+json`, in which case the requested
+// type is returned. The *first* item in allowed is preferred if there is a
+// tie. If nothing matches, returns an empty string. This does not allocate.
+func SelectMediaType(header string, allowed []string) string {
+	best := ""
+	bestQ := 0.0
 
-			found := false
-			for _, n := range allowed {
-				if n == name {
-					found = true
-					break
+	for _, a := range allowed {
+		if !strings.Contains(a, "/") {
+			// Structured syntax suffix, e.g. `json` for `application/foo+json`.
+			for pos := 0; pos != -1 && header != ""; {
+				var name string
+				var q float64
+				name, q, pos = nextValue(header, pos)
+				if q > bestQ && len(name) > len(a)+1 && name[len(name)-len(a)-1] == '+' && strings.EqualFold(name[len(name)-len(a):], a) && !strings.Contains(name, "*") {
+					best = name
+					bestQ = q
 				}
 			}
+			continue
+		}
 
-			if !found {
-				// Skip formats we don't support.
-				continue
-			}
+		q := mediaTypeQ(header, a)
+		if q > bestQ {
+			best = a
+			bestQ = q
+		}
+	}
 
-			if q > bestQ || (q == bestQ && name == allowed[0]) {
-				bestQ = q
-				best = name
-			}
+	return best
+}
+
+// mediaTypeQ returns the quality value for a concrete media type given an
+// `Accept` header, using the most specific matching media range.
+func mediaTypeQ(header, mediaType string) float64 {
+	slash := strings.IndexByte(mediaType, '/')
+	typ := mediaType[:slash]
+
+	q := 0.0
+	specificity := 0
+	for pos := 0; pos != -1 && header != ""; {
+		var name string
+		var nameQ float64
+		name, nameQ, pos = nextValue(header, pos)
+
+		s := 0
+		switch {
+		case strings.EqualFold(name, mediaType):
+			s = 3
+		case len(name) == len(typ)+2 && strings.EqualFold(name[:len(typ)], typ) && name[len(typ):] == "/*":
+			s = 2
+		case name == "*/*" || name == "*":
+			s = 1
+		}
+		if s > specificity {
+			specificity = s
+			q = nameQ
+		}
+	}
+	return q
+}
+
+// SelectLanguage selects the best language tag from the available set given
+// an `Accept-Language` header, using RFC 4647 language range matching. Each
+// language range in the header is tried from highest to lowest quality:
+// first as an exact (case-insensitive) match, then as a prefix of an
+// available tag (`de` matches `de-DE`), and finally by progressively
+// truncating the range (`de-CH-1996` matches `de`). The wildcard `*` matches
+// the first available tag. The *first* item in available is preferred if
+// there is a tie. If nothing matches, returns an empty string.
+func SelectLanguage(header string, available []string) string {
+	best := ""
+	bestQ := 0.0
+
+	for pos := 0; pos != -1 && header != ""; {
+		var name string
+		var q float64
+		name, q, pos = nextValue(header, pos)
+		if q <= bestQ || name == "" {
 			continue
 		}
 
-		if char != ' ' && char != '\t' {
-			end = pos
-			if start == -1 {
-				start = pos
+		if tag := lookupLanguage(name, available); tag != "" {
+			best = tag
+			bestQ = q
+		}
+	}
+
+	return best
+}
+
+// lookupLanguage finds the best available tag for a single language range.
+func lookupLanguage(lang string, available []string) string {
+	if lang == "*" {
+		if len(available) > 0 {
+			return available[0]
+		}
+		return ""
+	}
+
+	// Exact match.
+	for _, tag := range available {
+		if strings.EqualFold(tag, lang) {
+			return tag
+		}
+	}
+
+	// Basic filtering: the range is a prefix of the tag.
+	for _, tag := range available {
+		if len(tag) > len(lang) && tag[len(lang)] == '-' && strings.EqualFold(tag[:len(lang)], lang) {
+			return tag
+		}
+	}
+
+	// Lookup: progressively truncate the range.
+	for {
+		i := strings.LastIndexByte(lang, '-')
+		if i == -1 {
+			break
+		}
+		lang = lang[:i]
+		if len(lang) > 1 && lang[len(lang)-2] == '-' {
+			// Remove single-letter extension subtags like `x` in `de-x-foo`.
+			lang = lang[:len(lang)-2]
+		}
+		for _, tag := range available {
+			if strings.EqualFold(tag, lang) {
+				return tag
 			}
 		}
 	}
 
+	return ""
+}
+
+// SelectEncoding selects the best content coding from the allowed set given
+// an `Accept-Encoding` header, following the RFC 7231 rules for `identity`
+// and `*`. The `identity` coding (i.e. no encoding) is returned when the
+// header is empty, when no allowed coding is acceptable, or when it is the
+// best match. An empty string is returned only when the client has refused
+// all allowed codings *and* `identity`, e.g. via `*;q=0`, in which case the
+// server should respond with 406 Not Acceptable or ignore the header. The
+// *first* item in allowed is preferred if there is a tie. This does not
+// allocate.
+func SelectEncoding(header string, allowed []string) string {
+	if header == "" {
+		return "identity"
+	}
+
+	best := ""
+	bestQ := 0.0
+	for _, coding := range allowed {
+		if coding == "identity" {
+			continue
+		}
+		if q, _ := encodingQ(header, coding); q > bestQ {
+			best = coding
+			bestQ = q
+		}
+	}
+
+	identityQ, found := encodingQ(header, "identity")
+	if !found {
+		// Identity is always acceptable unless explicitly refused, but prefer
+		// any acceptable allowed coding over it.
+		identityQ = 0.001
+	}
+	if identityQ > bestQ {
+		return "identity"
+	}
+
 	return best
 }
+
+// encodingQ returns the quality value for a content coding, falling back to
+// the value of `*` if the coding is not explicitly listed. The second return
+// value is false if neither the coding nor `*` are present.
+func encodingQ(header, coding string) (float64, bool) {
+	q := 0.0
+	found := false
+	for pos := 0; pos != -1; {
+		var name string
+		var nameQ float64
+		name, nameQ, pos = nextValue(header, pos)
+		if strings.EqualFold(name, coding) {
+			return nameQ, true
+		}
+		if name == "*" {
+			q = nameQ
+			found = true
+		}
+	}
+	return q, found
+}
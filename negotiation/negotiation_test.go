@@ -50,6 +50,97 @@ func TestNoMatchFast(t *testing.T) {
 	assert.Equal(t, "", SelectQValueFast("a; q=1.0, b;q=1.0,c; q=0.3", []string{"d", "e"}))
 }
 
+func TestAcceptFastQLast(t *testing.T) {
+	assert.Equal(t, "b", SelectQValueFast("a;q=0.1, b;q=0.5", []string{"a", "b"}))
+	assert.Equal(t, "c", SelectQValueFast("a;q=0.5, b, c", []string{"a", "c"}))
+	assert.Equal(t, "", SelectQValueFast("a;q=0", []string{"a"}))
+	assert.Equal(t, "", SelectQValueFast("", []string{"a"}))
+}
+
+func TestSelectMediaType(t *testing.T) {
+	for _, item := range []struct {
+		header   string
+		allowed  []string
+		expected string
+	}{
+		{"application/json", []string{"application/json", "application/cbor"}, "application/json"},
+		{"application/cbor;q=0.9, application/json;q=0.5", []string{"application/json", "application/cbor"}, "application/cbor"},
+		{"*/*", []string{"application/json", "application/cbor"}, "application/json"},
+		{"text/html, application/*;q=0.8", []string{"application/json", "application/cbor"}, "application/json"},
+		{"application/*;q=0.8, application/json;q=0.2", []string{"application/json", "application/cbor"}, "application/cbor"},
+		{"application/vnd.foo+json", []string{"application/json", "json", "cbor"}, "application/vnd.foo+json"},
+		{"application/vnd.foo+cbor, application/json;q=0.5", []string{"application/json", "json", "cbor"}, "application/vnd.foo+cbor"},
+		{"application/*+json", []string{"application/json", "json"}, ""},
+		{"APPLICATION/JSON", []string{"application/json"}, "application/json"},
+		{"application/json;q=0", []string{"application/json"}, ""},
+		{"text/html", []string{"application/json"}, ""},
+		{"", []string{"application/json"}, ""},
+	} {
+		t.Run(item.header, func(t *testing.T) {
+			assert.Equal(t, item.expected, SelectMediaType(item.header, item.allowed))
+		})
+	}
+}
+
+func TestSelectLanguage(t *testing.T) {
+	for _, item := range []struct {
+		header    string
+		available []string
+		expected  string
+	}{
+		{"de", []string{"en", "de"}, "de"},
+		{"de-DE, de;q=0.9", []string{"en", "de"}, "de"},
+		{"de-CH-1996", []string{"en", "de"}, "de"},
+		{"de-x-foo", []string{"en", "de"}, "de"},
+		{"de", []string{"en", "de-DE"}, "de-DE"},
+		{"DE-de", []string{"en", "de-DE"}, "de-DE"},
+		{"fr, ja;q=0.5", []string{"en", "ja"}, "ja"},
+		{"ja;q=0.5, de;q=0.8", []string{"ja", "de"}, "de"},
+		{"*", []string{"en", "ja"}, "en"},
+		{"fr", []string{"en", "ja"}, ""},
+		{"de;q=0", []string{"de"}, ""},
+	} {
+		t.Run(item.header, func(t *testing.T) {
+			assert.Equal(t, item.expected, SelectLanguage(item.header, item.available))
+		})
+	}
+}
+
+func TestSelectEncoding(t *testing.T) {
+	for _, item := range []struct {
+		header   string
+		allowed  []string
+		expected string
+	}{
+		{"", []string{"br", "gzip"}, "identity"},
+		{"gzip", []string{"br", "gzip"}, "gzip"},
+		{"gzip, deflate, br", []string{"br", "gzip"}, "br"},
+		{"gzip;q=1.0, br;q=0.5", []string{"br", "gzip"}, "gzip"},
+		{"*", []string{"br", "gzip"}, "br"},
+		{"br;q=0, *", []string{"br", "gzip"}, "gzip"},
+		{"gzip;q=0", []string{"gzip"}, "identity"},
+		{"deflate", []string{"gzip"}, "identity"},
+		{"identity;q=1, gzip;q=0.5", []string{"gzip"}, "identity"},
+		{"*;q=0", []string{"gzip"}, ""},
+		{"identity;q=0", []string{"gzip"}, ""},
+		{"gzip, identity;q=0", []string{"gzip"}, "gzip"},
+	} {
+		t.Run(item.header, func(t *testing.T) {
+			assert.Equal(t, item.expected, SelectEncoding(item.header, item.allowed))
+		})
+	}
+}
+
+func TestNoAllocs(t *testing.T) {
+	header := "application/ion;q=0.6,application/json;q=0.5,application/yaml;q=0.5,text/*;q=0.2,application/vnd.foo+cbor;q=0.9,application/msgpack;q=0.8,*/*"
+	allowed := []string{"application/json", "application/cbor", "json", "cbor"}
+	assert.Zero(t, testing.AllocsPerRun(10, func() {
+		SelectMediaType(header, allowed)
+		SelectLanguage("de-CH, en;q=0.5", []string{"en", "de"})
+		SelectEncoding("gzip, deflate, br;q=0.9", []string{"br", "gzip"})
+	}))
+}
+
 var BenchResult string
 
 func BenchmarkMatch(b *testing.B) {
@@ -69,3 +160,12 @@ func BenchmarkMatchFast(b *testing.B) {
 		BenchResult = SelectQValueFast(header, allowed)
 	}
 }
+
+func BenchmarkSelectMediaType(b *testing.B) {
+	header := "application/ion;q=0.6,application/json;q=0.5,application/yaml;q=0.5,text/*;q=0.2,application/cbor;q=0.9,application/msgpack;q=0.8,*/*"
+	allowed := []string{"application/json", "application/yaml", "application/cbor"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		BenchResult = SelectMediaType(header, allowed)
+	}
+}
@@ -197,6 +197,17 @@ type Operation struct {
 	// of -1 can unset the server's timeout.
 	BodyReadTimeout time.Duration `yaml:"-"`
 
+	// Timeout is the maximum amount of time the handler may run. If not
+	// specified, `Config.Timeout` is used. Use -1 for unlimited. When reached,
+	// the handler's context is canceled and, unless the handler has already
+	// returned, an HTTP `TimeoutStatus` error is returned. Streaming responses
+	// such as SSE are not subject to the timeout.
+	Timeout time.Duration `yaml:"-"`
+
+	// TimeoutStatus is the HTTP status code returned when `Timeout` is
+	// reached, typically 503 (the default) or 504.
+	TimeoutStatus int `yaml:"-"`
+
 	// Errors is a list of HTTP status codes that the handler may return. If
 	// not specified, then a default error response is added to the OpenAPI.
 	Errors []int `yaml:"-"`
@@ -216,6 +227,43 @@ type Operation struct {
 	// you'd still like the benefits of using Huma. Generally not recommended.
 	Hidden bool `yaml:"-"`
 
+	// AutoETag enables automatic conditional `GET` & `HEAD` handling. A strong
+	// `ETag` header is generated by hashing the marshaled response body unless
+	// the output sets one or implements `huma.ETagger`, and a `304 Not
+	// Modified` is returned when `If-None-Match` matches. If the output
+	// implements `huma.LastModifier` then `Last-Modified` and
+	// `If-Modified-Since` are supported as well. Outputs which can cheaply
+	// provide these values avoid rendering the body for `304` responses.
+	AutoETag bool `yaml:"-"`
+
+	// IdempotencyKey enables `Idempotency-Key` header handling for unsafe
+	// operations like payments. The first request with a key runs the handler
+	// and its response status, headers, and body are saved in
+	// `Config.IdempotencyStore`. Retries with the same key and request body
+	// get the saved response, while reusing the key for a different request
+	// returns a `422 Unprocessable Entity` and concurrent duplicates return a
+	// `409 Conflict`. Server errors are not saved so they can be retried.
+	IdempotencyKey bool `yaml:"-"`
+
+	// IdempotencyKeyRequired rejects requests without an `Idempotency-Key`
+	// header with a `400 Bad Request`. It implies `IdempotencyKey`.
+	IdempotencyKeyRequired bool `yaml:"-"`
+
+	// Metadata is a map of arbitrary data that can be attached to the operation.
+	// This can be used to store custom data for use by middleware or other
+	// packages, e.g. `conditional.Require`, and is not documented.
+	Metadata map[string]any `yaml:"-"`
+
+	// Middlewares is a list of middleware functions to run before the handler.
+	// This is useful for adding custom logic to operations, such as logging,
+	// authentication, or rate limiting. They run after any API-wide middleware
+	// added via `api.UseMiddleware`.
+	Middlewares Middlewares `yaml:"-"`
+
+	// handler is the operation's request handler without any middleware, set
+	// by `huma.Register`.
+	handler func(ctx Context)
+
 	// OpenAPI fields
 
 	Tags         []string              `yaml:"tags,omitempty"`
@@ -233,6 +281,16 @@ type Operation struct {
 	Extensions   map[string]any        `yaml:",inline"`
 }
 
+// Handler returns the handler for an operation registered via `huma.Register`
+// which parses & validates the input, calls the operation's handler func, and
+// writes the response. It does not run any middleware. This enables calling
+// operations in-process with a custom `huma.Context`, e.g. as done by the
+// `autopatch` package. Returns `nil` for operations which were not registered
+// via `huma.Register`.
+func (o *Operation) Handler() func(ctx Context) {
+	return o.handler
+}
+
 type PathItem struct {
 	Ref         string         `yaml:"$ref,omitempty"`
 	Summary     string         `yaml:"summary,omitempty"`
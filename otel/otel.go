@@ -0,0 +1,271 @@
+// Package otel provides OpenTelemetry tracing and metrics for operations
+// registered via `huma.Register`. Each request creates a server span named
+// by the operation ID with a child span for each phase of handling the
+// request, and records request duration & body size histograms.
+//
+//	api.UseMiddleware(otel.New(api, otel.Options{}))
+package otel
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope name used for the tracer & meter.
+const ScopeName = "github.com/danielgtaylor/huma/v2/otel"
+
+// Attribute keys set on spans and metrics. HTTP attributes follow the
+// OpenTelemetry semantic conventions.
+const (
+	AttrOperationID      = attribute.Key("huma.operation.id")
+	AttrPhase            = attribute.Key("huma.phase")
+	AttrValidationErrors = attribute.Key("huma.validation.errors")
+	AttrMethod           = attribute.Key("http.request.method")
+	AttrRoute            = attribute.Key("http.route")
+	AttrStatus           = attribute.Key("http.response.status_code")
+	AttrRequestSize      = attribute.Key("http.request.body.size")
+	AttrResponseSize     = attribute.Key("http.response.body.size")
+)
+
+// Options configures the OpenTelemetry middleware.
+type Options struct {
+	// TracerProvider creates the tracer used for spans. Defaults to the global
+	// tracer provider.
+	TracerProvider trace.TracerProvider
+
+	// MeterProvider creates the meter used for metrics. Defaults to the global
+	// meter provider.
+	MeterProvider metric.MeterProvider
+
+	// Propagator extracts the parent span context from incoming request
+	// headers. Defaults to the global text map propagator.
+	Propagator propagation.TextMapPropagator
+
+	// DisablePhaseSpans turns off the child span for each phase of handling
+	// the request. Phase durations are still recorded as metrics.
+	DisablePhaseSpans bool
+}
+
+// instruments are the metric instruments used by the middleware.
+type instruments struct {
+	duration      metric.Float64Histogram
+	phaseDuration metric.Float64Histogram
+	requestSize   metric.Int64Histogram
+	responseSize  metric.Int64Histogram
+}
+
+func newInstruments(meter metric.Meter) (*instruments, error) {
+	var err error
+	i := &instruments{}
+	if i.duration, err = meter.Float64Histogram("http.server.request.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of HTTP server requests."),
+	); err != nil {
+		return nil, err
+	}
+	if i.phaseDuration, err = meter.Float64Histogram("huma.phase.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of each phase of handling a request."),
+	); err != nil {
+		return nil, err
+	}
+	if i.requestSize, err = meter.Int64Histogram("http.server.request.body.size",
+		metric.WithUnit("By"),
+		metric.WithDescription("Size of HTTP server request bodies."),
+	); err != nil {
+		return nil, err
+	}
+	if i.responseSize, err = meter.Int64Histogram("http.server.response.body.size",
+		metric.WithUnit("By"),
+		metric.WithDescription("Size of HTTP server response bodies."),
+	); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+// New creates a new OpenTelemetry middleware for the given API. It panics if
+// the metric instruments cannot be created.
+func New(api huma.API, opts Options) func(ctx huma.Context, next func(huma.Context)) {
+	if opts.TracerProvider == nil {
+		opts.TracerProvider = otel.GetTracerProvider()
+	}
+	if opts.MeterProvider == nil {
+		opts.MeterProvider = otel.GetMeterProvider()
+	}
+	if opts.Propagator == nil {
+		opts.Propagator = otel.GetTextMapPropagator()
+	}
+
+	tracer := opts.TracerProvider.Tracer(ScopeName)
+	inst, err := newInstruments(opts.MeterProvider.Meter(ScopeName))
+	if err != nil {
+		panic(err)
+	}
+
+	return func(ctx huma.Context, next func(huma.Context)) {
+		start := time.Now()
+		op := ctx.Operation()
+		name := op.OperationID
+		if name == "" {
+			name = op.Method + " " + op.Path
+		}
+		attrs := []attribute.KeyValue{
+			AttrMethod.String(ctx.Method()),
+			AttrRoute.String(op.Path),
+		}
+		if op.OperationID != "" {
+			attrs = append(attrs, AttrOperationID.String(op.OperationID))
+		}
+
+		parent := opts.Propagator.Extract(ctx.Context(), headerCarrier{ctx})
+		spanCtx, span := tracer.Start(parent, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attrs...),
+		)
+		defer span.End()
+
+		c := &otelContext{ContextWrapper: huma.WrapContext(ctx)}
+		validationErrors := 0
+		c.ctx = huma.WithPhaseHook(spanCtx, func(_ huma.Context, phase huma.Phase) func(error) {
+			phaseStart := time.Now()
+			var phaseSpan trace.Span
+			if !opts.DisablePhaseSpans {
+				_, phaseSpan = tracer.Start(spanCtx, string(phase))
+			}
+			return func(err error) {
+				if err != nil && phase == huma.PhaseValidate {
+					if u, ok := err.(interface{ Unwrap() []error }); ok {
+						validationErrors = len(u.Unwrap())
+					}
+				}
+				if phaseSpan != nil {
+					if err != nil {
+						phaseSpan.RecordError(err)
+						phaseSpan.SetStatus(codes.Error, err.Error())
+					}
+					phaseSpan.End()
+				}
+				inst.phaseDuration.Record(spanCtx, time.Since(phaseStart).Seconds(), metric.WithAttributes(append(attrs, AttrPhase.String(string(phase)))...))
+			}
+		})
+
+		next(c)
+
+		status := c.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		attrs = append(attrs, AttrStatus.Int(status))
+
+		span.SetAttributes(
+			AttrStatus.Int(status),
+			AttrRequestSize.Int64(c.read.Load()),
+			AttrResponseSize.Int64(c.written.Load()),
+		)
+		if validationErrors > 0 {
+			span.SetAttributes(AttrValidationErrors.Int(validationErrors))
+		}
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+
+		set := metric.WithAttributes(attrs...)
+		inst.duration.Record(spanCtx, time.Since(start).Seconds(), set)
+		inst.requestSize.Record(spanCtx, c.read.Load(), set)
+		inst.responseSize.Record(spanCtx, c.written.Load(), set)
+	}
+}
+
+// headerCarrier adapts request headers for trace context propagation.
+type headerCarrier struct {
+	ctx huma.Context
+}
+
+func (h headerCarrier) Get(key string) string {
+	return h.ctx.Header(key)
+}
+
+func (h headerCarrier) Set(key, value string) {}
+
+func (h headerCarrier) Keys() []string {
+	keys := []string{}
+	h.ctx.EachHeader(func(name, value string) {
+		keys = append(keys, name)
+	})
+	return keys
+}
+
+// otelContext wraps a `huma.Context` to propagate the span to the handler
+// and record the response status and body sizes.
+type otelContext struct {
+	huma.ContextWrapper
+	ctx     context.Context
+	status  int
+	read    atomic.Int64
+	written atomic.Int64
+}
+
+func (c *otelContext) Context() context.Context {
+	return c.ctx
+}
+
+func (c *otelContext) SetStatus(code int) {
+	c.status = code
+	c.Unwrap().SetStatus(code)
+}
+
+func (c *otelContext) BodyReader() io.Reader {
+	r := c.Unwrap().BodyReader()
+	if r == nil {
+		return nil
+	}
+	return &countingReader{r, &c.read}
+}
+
+func (c *otelContext) BodyWriter() io.Writer {
+	return &countingWriter{huma.WriterWrapper{Writer: c.Unwrap().BodyWriter()}, &c.written}
+}
+
+// countingReader counts the bytes read from the request body.
+type countingReader struct {
+	io.Reader
+	n *atomic.Int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n.Add(int64(n))
+	return n, err
+}
+
+// Close passes through to the underlying reader, if supported.
+func (r *countingReader) Close() error {
+	if c, ok := r.Reader.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// countingWriter counts the bytes written to the response body.
+type countingWriter struct {
+	huma.WriterWrapper
+	n *atomic.Int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	w.n.Add(int64(n))
+	return n, err
+}
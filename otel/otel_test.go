@@ -0,0 +1,174 @@
+package otel
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type ThingInput struct {
+	Body struct {
+		Name string `json:"name" minLength:"3"`
+		Size int    `json:"size" minimum:"1"`
+	}
+}
+
+type ThingOutput struct {
+	Body struct {
+		Name string `json:"name"`
+	}
+}
+
+func setup(t *testing.T) (humatest.TestAPI, *tracetest.SpanRecorder, *sdkmetric.ManualReader) {
+	_, api := humatest.New(t)
+
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	api.UseMiddleware(New(api, Options{
+		TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)),
+		MeterProvider:  sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+		Propagator:     propagation.TraceContext{},
+	}))
+
+	huma.Register(api, huma.Operation{
+		OperationID: "create-thing",
+		Method:      http.MethodPost,
+		Path:        "/things/{id}",
+	}, func(ctx context.Context, input *ThingInput) (*ThingOutput, error) {
+		// The handler's context carries the server span.
+		assert.True(t, trace.SpanContextFromContext(ctx).IsValid())
+		if input.Body.Name == "fail" {
+			return nil, huma.Error500InternalServerError("boom")
+		}
+		resp := &ThingOutput{}
+		resp.Body.Name = input.Body.Name
+		return resp, nil
+	})
+
+	return api, spans, reader
+}
+
+// attr finds an attribute value by key.
+func attr(attrs []attribute.KeyValue, key attribute.Key) attribute.Value {
+	for _, a := range attrs {
+		if a.Key == key {
+			return a.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracing(t *testing.T) {
+	api, spans, _ := setup(t)
+
+	resp := api.Post("/things/1",
+		"traceparent: 00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		strings.NewReader(`{"name": "widget", "size": 5}`))
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	ended := spans.Ended()
+	require.Len(t, ended, 6)
+
+	// Phase spans end first, then the server span.
+	server := ended[len(ended)-1]
+	assert.Equal(t, "create-thing", server.Name())
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind())
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", server.SpanContext().TraceID().String())
+	assert.Equal(t, "b7ad6b7169203331", server.Parent().SpanID().String())
+	assert.Equal(t, "POST", attr(server.Attributes(), AttrMethod).AsString())
+	assert.Equal(t, "/things/{id}", attr(server.Attributes(), AttrRoute).AsString())
+	assert.Equal(t, "create-thing", attr(server.Attributes(), AttrOperationID).AsString())
+	assert.EqualValues(t, 200, attr(server.Attributes(), AttrStatus).AsInt64())
+	assert.EqualValues(t, 29, attr(server.Attributes(), AttrRequestSize).AsInt64())
+	assert.EqualValues(t, resp.Body.Len(), attr(server.Attributes(), AttrResponseSize).AsInt64())
+
+	names := []string{}
+	for _, s := range ended[:len(ended)-1] {
+		names = append(names, s.Name())
+		assert.Equal(t, server.SpanContext().SpanID(), s.Parent().SpanID())
+	}
+	assert.Equal(t, []string{"params", "body", "validate", "handler", "marshal"}, names)
+}
+
+func TestTracingErrors(t *testing.T) {
+	api, spans, _ := setup(t)
+
+	resp := api.Post("/things/1", strings.NewReader(`{"name": "a", "size": 0}`))
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.Code)
+
+	ended := spans.Ended()
+	server := ended[len(ended)-1]
+	assert.EqualValues(t, 422, attr(server.Attributes(), AttrStatus).AsInt64())
+	assert.EqualValues(t, 2, attr(server.Attributes(), AttrValidationErrors).AsInt64())
+	assert.Equal(t, codes.Unset, server.Status().Code)
+
+	validate := ended[len(ended)-2]
+	assert.Equal(t, "validate", validate.Name())
+	assert.Equal(t, codes.Error, validate.Status().Code)
+
+	resp = api.Post("/things/1", strings.NewReader(`{"name": "fail", "size": 1}`))
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+
+	ended = spans.Ended()
+	server = ended[len(ended)-1]
+	assert.Equal(t, codes.Error, server.Status().Code)
+	handler := ended[len(ended)-3]
+	assert.Equal(t, "handler", handler.Name())
+	assert.Equal(t, codes.Error, handler.Status().Code)
+}
+
+func TestMetrics(t *testing.T) {
+	api, _, reader := setup(t)
+
+	api.Post("/things/1", strings.NewReader(`{"name": "widget", "size": 5}`))
+	api.Post("/things/2", strings.NewReader(`{"name": "widget", "size": 5}`))
+	api.Post("/things/3", strings.NewReader(`{"name": "a", "size": 5}`))
+
+	rm := metricdata.ResourceMetrics{}
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+	assert.Equal(t, ScopeName, rm.ScopeMetrics[0].Scope.Name)
+
+	metrics := map[string]metricdata.Metrics{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m
+	}
+
+	duration := metrics["http.server.request.duration"].Data.(metricdata.Histogram[float64])
+	counts := map[int64]uint64{}
+	for _, dp := range duration.DataPoints {
+		status, _ := dp.Attributes.Value(AttrStatus)
+		counts[status.AsInt64()] += dp.Count
+	}
+	assert.Equal(t, map[int64]uint64{200: 2, 422: 1}, counts)
+
+	phases := metrics["huma.phase.duration"].Data.(metricdata.Histogram[float64])
+	seen := map[string]uint64{}
+	for _, dp := range phases.DataPoints {
+		phase, _ := dp.Attributes.Value(AttrPhase)
+		seen[phase.AsString()] += dp.Count
+	}
+	assert.Equal(t, map[string]uint64{"params": 3, "body": 3, "validate": 3, "handler": 2, "marshal": 2}, seen)
+
+	size := metrics["http.server.request.body.size"].Data.(metricdata.Histogram[int64])
+	total := int64(0)
+	for _, dp := range size.DataPoints {
+		total += dp.Sum
+	}
+	assert.EqualValues(t, 29+29+24, total)
+	assert.Contains(t, metrics, "http.server.response.body.size")
+}
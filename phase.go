@@ -0,0 +1,102 @@
+package huma
+
+import (
+	"context"
+	"errors"
+)
+
+// Phase is a stage of handling a request for an operation registered via
+// `huma.Register`.
+type Phase string
+
+const (
+	// PhaseParams parses & validates path, query, and header parameters.
+	PhaseParams Phase = "params"
+
+	// PhaseBody reads the request body. It is skipped for operations without
+	// an input body.
+	PhaseBody Phase = "body"
+
+	// PhaseValidate parses & validates the request body and runs resolvers.
+	// If validation fails, the error passed to the end function wraps each
+	// validation error and can be unwrapped via `Unwrap() []error`.
+	PhaseValidate Phase = "validate"
+
+	// PhaseHandler calls the operation handler.
+	PhaseHandler Phase = "handler"
+
+	// PhaseMarshal writes the response headers and marshals the response body.
+	PhaseMarshal Phase = "marshal"
+)
+
+// PhaseHook is called when an operation starts a phase of handling a request
+// and returns a function which is called when that phase ends, with the
+// error which ended the request, if any. Hooks are used for tracing and
+// metrics to see where request latency goes.
+type PhaseHook func(ctx Context, phase Phase) (end func(err error))
+
+type phaseHookKey struct{}
+
+// WithPhaseHook returns a copy of the context which calls the hook for each
+// phase of handling the request, after any hooks already in the context. Use
+// `huma.WithContext` to set it from middleware:
+//
+//	api.UseMiddleware(func(ctx huma.Context, next func(huma.Context)) {
+//		next(huma.WithContext(ctx, huma.WithPhaseHook(ctx.Context(), func(ctx huma.Context, phase huma.Phase) func(error) {
+//			start := time.Now()
+//			return func(err error) {
+//				fmt.Println(phase, time.Since(start))
+//			}
+//		})))
+//	})
+func WithPhaseHook(ctx context.Context, hook PhaseHook) context.Context {
+	if prev, ok := ctx.Value(phaseHookKey{}).(PhaseHook); ok {
+		next := hook
+		hook = func(ctx Context, phase Phase) func(error) {
+			endPrev := prev(ctx, phase)
+			endNext := next(ctx, phase)
+			return func(err error) {
+				endNext(err)
+				endPrev(err)
+			}
+		}
+	}
+	return context.WithValue(ctx, phaseHookKey{}, hook)
+}
+
+// phases tracks the current phase of a request, calling the hook from the
+// request context, if any, as phases start and end.
+type phases struct {
+	ctx  Context
+	hook PhaseHook
+	end  func(error)
+}
+
+func newPhases(ctx Context) phases {
+	hook, _ := ctx.Context().Value(phaseHookKey{}).(PhaseHook)
+	return phases{ctx: ctx, hook: hook}
+}
+
+// start ends the current phase, if any, and starts the next one.
+func (p *phases) start(phase Phase) {
+	if p.hook == nil {
+		return
+	}
+	p.done(nil)
+	p.end = p.hook(p.ctx, phase)
+}
+
+// done ends the current phase with an optional error.
+func (p *phases) done(err error) {
+	if p.end != nil {
+		p.end(err)
+		p.end = nil
+	}
+}
+
+// fail ends the current phase with the validation errors.
+func (p *phases) fail(errs []error) {
+	if p.end != nil {
+		p.done(errors.Join(errs...))
+	}
+}
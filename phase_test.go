@@ -0,0 +1,76 @@
+package huma
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type ctxKey struct{}
+
+func TestPhaseHooks(t *testing.T) {
+	r := chi.NewRouter()
+	api := NewTestAdapter(r, DefaultConfig("Test API", "1.0.0"))
+
+	calls := []string{}
+	hook := func(name string) PhaseHook {
+		return func(ctx Context, phase Phase) func(error) {
+			calls = append(calls, name+" start "+string(phase))
+			return func(err error) {
+				msg := name + " end " + string(phase)
+				if err != nil {
+					msg += ": " + err.Error()
+				}
+				calls = append(calls, msg)
+			}
+		}
+	}
+
+	api.UseMiddleware(func(ctx Context, next func(Context)) {
+		c := WithPhaseHook(ctx.Context(), hook("a"))
+		c = WithPhaseHook(c, hook("b"))
+		next(WithContext(ctx, context.WithValue(c, ctxKey{}, "value")))
+	})
+
+	Register(api, Operation{
+		Method: http.MethodPut,
+		Path:   "/things/{id}",
+	}, func(ctx context.Context, input *struct {
+		ID   string `path:"id"`
+		Body struct {
+			Count int `json:"count" minimum:"1"`
+		}
+	}) (*struct{}, error) {
+		// Values set via `WithContext` reach the handler.
+		assert.Equal(t, "value", ctx.Value(ctxKey{}))
+		return nil, nil
+	})
+
+	do := func(body string) int {
+		req, _ := http.NewRequest(http.MethodPut, "/things/1", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusNoContent, do(`{"count": 1}`))
+	expected := []string{}
+	for _, phase := range []Phase{PhaseParams, PhaseBody, PhaseValidate, PhaseHandler, PhaseMarshal} {
+		expected = append(expected,
+			"a start "+string(phase), "b start "+string(phase),
+			"b end "+string(phase), "a end "+string(phase),
+		)
+	}
+	assert.Equal(t, expected, calls)
+
+	// Validation failures end the validate phase with the joined errors.
+	calls = nil
+	assert.Equal(t, http.StatusUnprocessableEntity, do(`{"count": 0}`))
+	assert.Len(t, calls, 12)
+	assert.Equal(t, "a end validate: expected number >= 1 (body.count: 0)", calls[11])
+}
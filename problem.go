@@ -0,0 +1,367 @@
+package huma
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/danielgtaylor/casing"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// ProblemType describes a documented RFC 7807 problem type. Problem types
+// give clients a stable URI to switch on and a human-readable page which
+// explains what went wrong and how to fix it. They may optionally define
+// extension members which are sent alongside the standard problem fields.
+//
+//	type OutOfCredit struct {
+//		Balance int      `json:"balance" doc:"Current account balance"`
+//		Accounts []string `json:"accounts" doc:"Accounts with credit"`
+//	}
+//
+//	var ErrOutOfCredit = huma.Problems.Register(huma.ProblemType{
+//		Name:        "out-of-credit",
+//		Title:       "You do not have enough credit.",
+//		Status:      http.StatusForbidden,
+//		Description: "Top up your account balance and try again.",
+//		Extensions:  OutOfCredit{},
+//	})
+type ProblemType struct {
+	// Name is a short unique identifier for this problem type, e.g.
+	// `out-of-credit`. It is used to build the default URI and the path of
+	// the human-readable documentation page.
+	Name string
+
+	// URI identifies the problem type and is sent to clients as the `type`
+	// member. If not set, it defaults to the registry prefix plus the name.
+	URI string
+
+	// Title is a short, human-readable summary of the problem type. If not
+	// set, it defaults to the HTTP status text.
+	Title string
+
+	// Status is the HTTP status code used for this problem type.
+	Status int
+
+	// Description is a longer explanation of the problem type, which is
+	// rendered on the problem type's documentation page.
+	Description string
+
+	// Default makes this the problem type used by `NewError` whenever an error
+	// is created for `Status` without a more specific problem type.
+	Default bool
+
+	// Extensions is an optional struct value whose fields describe extension
+	// members for this problem type. It is only used for documentation.
+	// Members named like a standard problem member, e.g. `status`, are never
+	// sent.
+	Extensions any
+
+	// page is the rendered documentation page.
+	page []byte
+}
+
+// New creates a new error instance for this problem type with the given
+// detail message, extension member values, and optional error details. It
+// uses `NewError` to create the error, so the type, title, and extension
+// members are only set if `NewError` returns an `*ErrorModel`.
+//
+//	return nil, ErrOutOfCredit.New("Your current balance is 30.", map[string]any{
+//		"balance": 30,
+//		"accounts": []string{"/account/12345", "/account/67890"},
+//	})
+func (pt *ProblemType) New(msg string, extensions map[string]any, errs ...error) StatusError {
+	err := NewError(pt.Status, msg, errs...)
+	if model, ok := err.(*ErrorModel); ok {
+		model.Type = pt.URI
+		model.Title = pt.Title
+		model.Extensions = extensions
+	}
+	return err
+}
+
+// ProblemRegistry stores problem types by name and URI. It is not safe for
+// concurrent modification, so all problem types should be registered at
+// service startup before any operations are registered.
+type ProblemRegistry struct {
+	prefix   string
+	types    map[string]*ProblemType
+	names    []string
+	defaults map[int]*ProblemType
+}
+
+// NewProblemRegistry creates a new problem type registry. The `prefix` is
+// used to generate URIs for problem types without an explicit URI, e.g. a
+// prefix of `/problems/` will give `out-of-credit` a URI of
+// `/problems/out-of-credit`.
+func NewProblemRegistry(prefix string) *ProblemRegistry {
+	return &ProblemRegistry{
+		prefix:   prefix,
+		types:    map[string]*ProblemType{},
+		defaults: map[int]*ProblemType{},
+	}
+}
+
+// Problems is the default problem type registry. It is used by `NewError` to
+// look up default problem types and by the API to serve problem type pages
+// and document extension members.
+var Problems = NewProblemRegistry("/problems/")
+
+// Register a new problem type, returning the stored problem type which can be
+// used to create new errors. Since registration happens at service startup,
+// this method panics on misconfiguration.
+func (r *ProblemRegistry) Register(pt ProblemType) *ProblemType {
+	if pt.Name == "" {
+		panic("problem type name must be specified")
+	}
+	if pt.Status == 0 {
+		panic("problem type status must be specified")
+	}
+	if _, ok := r.types[pt.Name]; ok {
+		panic("duplicate problem type " + pt.Name)
+	}
+	if pt.URI == "" {
+		pt.URI = r.prefix + pt.Name
+	}
+	if pt.Title == "" {
+		pt.Title = http.StatusText(pt.Status)
+	}
+	if pt.Extensions != nil && deref(reflect.TypeOf(pt.Extensions)).Kind() != reflect.Struct {
+		panic("problem type extensions must be a struct")
+	}
+
+	// Render the page now so serving it never touches a shared schema
+	// registry, which is not safe for concurrent use.
+	pt.page = renderProblemPage(&pt)
+
+	stored := &pt
+	r.types[pt.Name] = stored
+	r.names = append(r.names, pt.Name)
+	if pt.Default {
+		r.defaults[pt.Status] = stored
+	}
+	return stored
+}
+
+// Get returns the problem type with the given name or `nil` if it has not
+// been registered.
+func (r *ProblemRegistry) Get(name string) *ProblemType {
+	return r.types[name]
+}
+
+// ForStatus returns the default problem type for the given status code or
+// `nil` if no default has been registered.
+func (r *ProblemRegistry) ForStatus(status int) *ProblemType {
+	return r.defaults[status]
+}
+
+// All returns all registered problem types in registration order.
+func (r *ProblemRegistry) All() []*ProblemType {
+	all := make([]*ProblemType, 0, len(r.names))
+	for _, name := range r.names {
+		all = append(all, r.types[name])
+	}
+	return all
+}
+
+// schema returns a response schema for errors with the given status code. If
+// any problem types with extension members use this status, then the schema
+// will be a `oneOf` of the base error schema and a schema for each of the
+// problem types. Otherwise, the base error schema is returned unchanged.
+func (r *ProblemRegistry) schema(registry Registry, errType reflect.Type, errSchema *Schema, status int) *Schema {
+	schemas := []*Schema{}
+	for _, name := range r.names {
+		pt := r.types[name]
+		if pt.Status != status || pt.Extensions == nil {
+			continue
+		}
+		schemas = append(schemas, registry.Schema(problemStruct(errType, reflect.TypeOf(pt.Extensions)), true, casing.Camel(pt.Name)+"Problem"))
+	}
+
+	if len(schemas) == 0 {
+		return errSchema
+	}
+
+	return &Schema{
+		Extensions: map[string]any{
+			"oneOf": append([]*Schema{errSchema}, schemas...),
+		},
+	}
+}
+
+// problemStruct creates a new struct type which combines the fields of the
+// error model with the fields of the extension members, enabling the normal
+// schema generation & registry to be used for documentation.
+func problemStruct(errType, extType reflect.Type) reflect.Type {
+	fields := []reflect.StructField{}
+	seen := map[string]bool{}
+	for _, t := range []reflect.Type{deref(errType), deref(extType)} {
+		if t.Kind() != reflect.Struct {
+			continue
+		}
+		for _, info := range getFields(t) {
+			f := info.Field
+			if seen[f.Name] {
+				continue
+			}
+			seen[f.Name] = true
+			fields = append(fields, reflect.StructField{
+				Name: f.Name,
+				Type: f.Type,
+				Tag:  f.Tag,
+			})
+		}
+	}
+	return reflect.StructOf(fields)
+}
+
+// servePage writes the human-readable HTML page describing a problem type.
+func (r *ProblemRegistry) servePage(ctx Context, name string) {
+	ctx.SetHeader("Content-Type", "text/html")
+	pt := r.types[name]
+	if pt == nil {
+		ctx.SetStatus(http.StatusNotFound)
+		ctx.BodyWriter().Write([]byte("<!doctype html><html><body><h1>Unknown problem type</h1></body></html>"))
+		return
+	}
+	ctx.BodyWriter().Write(pt.page)
+}
+
+// renderProblemPage renders a human-readable HTML page describing a problem
+// type. Extension members are documented using a private schema registry so
+// that their schemas do not leak into any API's OpenAPI document.
+func renderProblemPage(pt *ProblemType) []byte {
+	buf := &bytes.Buffer{}
+	title := html.EscapeString(pt.Title)
+	buf.WriteString(`<!doctype html>
+<html lang="en">
+  <head>
+    <meta charset="utf-8">
+    <title>` + title + `</title>
+  </head>
+  <body>
+    <h1>` + title + `</h1>
+    <p><strong>Type:</strong> <code>` + html.EscapeString(pt.URI) + `</code></p>
+    <p><strong>Status:</strong> ` + strconv.Itoa(pt.Status) + " " + html.EscapeString(http.StatusText(pt.Status)) + `</p>
+`)
+	if pt.Description != "" {
+		buf.WriteString("    <p>" + html.EscapeString(pt.Description) + "</p>\n")
+	}
+
+	if pt.Extensions != nil {
+		registry := NewMapRegistry("#/components/schemas/", DefaultSchemaNamer)
+		s := registry.Schema(reflect.TypeOf(pt.Extensions), false, casing.Camel(pt.Name)+"Extensions")
+		names := make([]string, 0, len(s.Properties))
+		for k := range s.Properties {
+			if !standardProblemMembers[k] {
+				names = append(names, k)
+			}
+		}
+		sort.Strings(names)
+
+		buf.WriteString("    <h2>Extension members</h2>\n    <table>\n      <tr><th>Name</th><th>Type</th><th>Description</th></tr>\n")
+		for _, k := range names {
+			prop := s.Properties[k]
+			typ := prop.Type
+			if prop.Ref != "" {
+				typ = path.Base(prop.Ref)
+			}
+			buf.WriteString(fmt.Sprintf("      <tr><td><code>%s</code></td><td>%s</td><td>%s</td></tr>\n", html.EscapeString(k), html.EscapeString(typ), html.EscapeString(prop.Description)))
+		}
+		buf.WriteString("    </table>\n")
+	}
+	buf.WriteString("  </body>\n</html>")
+	return buf.Bytes()
+}
+
+// standardProblemMembers are the members defined by RFC 7807 and huma's error
+// model, which extension members may not replace.
+var standardProblemMembers = map[string]bool{
+	"type":     true,
+	"title":    true,
+	"status":   true,
+	"detail":   true,
+	"instance": true,
+	"errors":   true,
+	"$schema":  true,
+}
+
+// extender is implemented by response bodies which carry additional members
+// that should be marshaled inline with the body's own fields.
+type extender interface {
+	extensionMembers() map[string]any
+}
+
+// withExtensions marshals a value and then merges extension members into the
+// resulting object. This is used for problem extension members, which must
+// survive transformers that wrap the original response body.
+type withExtensions struct {
+	v   any
+	ext map[string]any
+}
+
+func (w *withExtensions) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal(w.v)
+	if err != nil || len(w.ext) == 0 {
+		return b, err
+	}
+	b = bytes.TrimSpace(b)
+	var present map[string]json.RawMessage
+	if len(b) < 2 || b[0] != '{' || json.Unmarshal(b, &present) != nil {
+		return nil, fmt.Errorf("cannot add extension members to %s", b)
+	}
+	ext := w.members(func(k string) bool {
+		_, ok := present[k]
+		return ok
+	})
+	if len(ext) == 0 {
+		return b, nil
+	}
+	eb, err := json.Marshal(ext)
+	if err != nil {
+		return nil, err
+	}
+	if len(present) == 0 {
+		return eb, nil
+	}
+	return append(append(b[:len(b)-1], ','), eb[1:]...), nil
+}
+
+func (w *withExtensions) MarshalCBOR() ([]byte, error) {
+	b, err := cborEncMode.Marshal(w.v)
+	if err != nil || len(w.ext) == 0 {
+		return b, err
+	}
+	m := map[string]any{}
+	if err := cbor.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	ext := w.members(func(k string) bool {
+		_, ok := m[k]
+		return ok
+	})
+	for k, v := range ext {
+		m[k] = v
+	}
+	return cborEncMode.Marshal(m)
+}
+
+// members returns the extension members which do not collide with standard
+// problem members or members already present in the marshaled value, which
+// would otherwise produce duplicate keys.
+func (w *withExtensions) members(present func(string) bool) map[string]any {
+	ext := make(map[string]any, len(w.ext))
+	for k, v := range w.ext {
+		if standardProblemMembers[k] || present(k) {
+			continue
+		}
+		ext[k] = v
+	}
+	return ext
+}
@@ -0,0 +1,167 @@
+package huma
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type OutOfCredit struct {
+	Balance  int      `json:"balance" doc:"Current account balance"`
+	Accounts []string `json:"accounts,omitempty" doc:"Accounts with credit"`
+}
+
+func TestProblemTypes(t *testing.T) {
+	orig := Problems
+	Problems = NewProblemRegistry("/problems/")
+	defer func() { Problems = orig }()
+
+	outOfCredit := Problems.Register(ProblemType{
+		Name:        "out-of-credit",
+		Title:       "You do not have enough credit.",
+		Status:      http.StatusForbidden,
+		Description: "Top up your <account> and try again.",
+		Extensions:  OutOfCredit{},
+	})
+	Problems.Register(ProblemType{
+		Name:    "not-found",
+		Status:  http.StatusNotFound,
+		Default: true,
+	})
+
+	assert.Equal(t, "/problems/out-of-credit", outOfCredit.URI)
+	assert.Same(t, outOfCredit, Problems.Get("out-of-credit"))
+	assert.Nil(t, Problems.ForStatus(http.StatusForbidden))
+	assert.Len(t, Problems.All(), 2)
+
+	assert.Panics(t, func() {
+		Problems.Register(ProblemType{Name: "not-found", Status: http.StatusNotFound})
+	})
+	assert.Panics(t, func() {
+		Problems.Register(ProblemType{Name: "bad", Status: 400, Extensions: "bad"})
+	})
+
+	// Default problem types are applied to all errors with that status.
+	err := Error404NotFound("missing").(*ErrorModel)
+	assert.Equal(t, "/problems/not-found", err.Type)
+	assert.Equal(t, "Not Found", err.Title)
+
+	r := chi.NewRouter()
+	config := DefaultConfig("Test API", "1.0.0")
+	config.ProblemsPath = "/problems"
+	api := NewTestAdapter(r, config)
+
+	Register(api, Operation{
+		OperationID: "buy",
+		Method:      http.MethodPost,
+		Path:        "/buy",
+		Errors:      []int{http.StatusForbidden},
+	}, func(ctx context.Context, input *struct{}) (*struct{}, error) {
+		return nil, outOfCredit.New("Your current balance is 30.", map[string]any{
+			"balance": 30,
+		})
+	})
+
+	req, _ := http.NewRequest(http.MethodPost, "/buy", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+
+	var body map[string]any
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "/problems/out-of-credit", body["type"])
+	assert.Equal(t, "You do not have enough credit.", body["title"])
+	assert.EqualValues(t, 30, body["balance"])
+	assert.Contains(t, body, "$schema")
+
+	req, _ = http.NewRequest(http.MethodPost, "/buy", nil)
+	req.Header.Set("Accept", "application/cbor")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, "application/problem+cbor", w.Header().Get("Content-Type"))
+	var cborBody map[string]any
+	assert.NoError(t, cbor.Unmarshal(w.Body.Bytes(), &cborBody))
+	assert.EqualValues(t, 30, cborBody["balance"])
+
+	// Extension members are documented in the OpenAPI.
+	schema := api.OpenAPI().Paths["/buy"].Post.Responses["403"].Content["application/problem+json"].Schema
+	oneOf := schema.Extensions["oneOf"].([]*Schema)
+	assert.Len(t, oneOf, 2)
+	problemSchema := api.OpenAPI().Components.Schemas.SchemaFromRef(oneOf[1].Ref)
+	assert.Contains(t, problemSchema.Properties, "balance")
+	assert.Contains(t, problemSchema.Properties, "detail")
+
+	// Human-readable problem pages.
+	req, _ = http.NewRequest(http.MethodGet, "/problems/out-of-credit", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/html", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "You do not have enough credit.")
+	assert.Contains(t, w.Body.String(), "&lt;account&gt;")
+	assert.Contains(t, w.Body.String(), "<code>balance</code>")
+
+	// Serving pages does not add schemas to the API.
+	assert.NotContains(t, api.OpenAPI().Components.Schemas.Map(), "OutOfCreditExtensions")
+
+	req, _ = http.NewRequest(http.MethodGet, "/problems/unknown", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestErrorModelExtensionsMarshal(t *testing.T) {
+	err := &ErrorModel{Status: 400}
+	b, _ := json.Marshal(err)
+	assert.JSONEq(t, `{"status": 400}`, string(b))
+
+	err.Extensions = map[string]any{"foo": "bar"}
+	b, _ = json.Marshal(err)
+	assert.JSONEq(t, `{"status": 400, "foo": "bar"}`, string(b))
+
+	b, _ = json.Marshal(&ErrorModel{Extensions: map[string]any{"foo": "bar"}})
+	assert.JSONEq(t, `{"foo": "bar"}`, string(b))
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, DefaultCBORFormat.Marshal(buf, err))
+	var decoded map[string]any
+	assert.NoError(t, cbor.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, "bar", decoded["foo"])
+}
+
+func TestErrorModelExtensionsCollision(t *testing.T) {
+	err := &ErrorModel{Status: 400, Extensions: map[string]any{
+		"status": 500,
+		"type":   "other",
+		"foo":    "bar",
+	}}
+	b, _ := json.Marshal(err)
+	assert.JSONEq(t, `{"status": 400, "foo": "bar"}`, string(b))
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, DefaultCBORFormat.Marshal(buf, err))
+	var decoded map[string]any
+	assert.NoError(t, cbor.Unmarshal(buf.Bytes(), &decoded))
+	assert.EqualValues(t, 400, decoded["status"])
+	assert.NotContains(t, decoded, "type")
+	assert.Equal(t, "bar", decoded["foo"])
+}
+
+func TestProblemsPathDisabled(t *testing.T) {
+	r := chi.NewRouter()
+	NewTestAdapter(r, DefaultConfig("Test API", "1.0.0"))
+
+	req, _ := http.NewRequest(http.MethodGet, "/problems/anything", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NotContains(t, w.Header().Get("Content-Type"), "text/html")
+}
@@ -0,0 +1,231 @@
+// Package ratelimit provides rate limiting for operations registered via
+// `huma.Register`. Requests are counted per client key, e.g. IP address, API
+// key, or authenticated principal, using a token bucket or sliding window in
+// a pluggable `Store`. Responses include the IETF `RateLimit-*` headers, and
+// limited requests get a `429 Too Many Requests` with a `Retry-After` header.
+// Both are documented in the OpenAPI for limited operations.
+//
+//	api.UseMiddleware(ratelimit.New(api, ratelimit.Options{
+//		Limit: ratelimit.Limit{Requests: 100, Window: time.Minute},
+//	}))
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+// MetadataKey is the operation metadata key used to override the default
+// limit for an operation. Set it to a `Limit`, or to `false` to disable rate
+// limiting for the operation:
+//
+//	huma.Register(api, huma.Operation{
+//		OperationID: "login",
+//		Method:      http.MethodPost,
+//		Path:        "/login",
+//		Metadata: map[string]any{
+//			ratelimit.MetadataKey: ratelimit.Limit{Requests: 5, Window: time.Minute},
+//		},
+//	}, handler)
+const MetadataKey = "ratelimit"
+
+// Response headers set by the middleware.
+const (
+	HeaderLimit      = "RateLimit-Limit"
+	HeaderRemaining  = "RateLimit-Remaining"
+	HeaderReset      = "RateLimit-Reset"
+	HeaderPolicy     = "RateLimit-Policy"
+	HeaderRetryAfter = "Retry-After"
+)
+
+// Options configures the rate limit middleware.
+type Options struct {
+	// Store records requests for each client. Defaults to a new in-memory
+	// store. Use a shared store when running multiple instances.
+	Store Store
+
+	// Key returns the client key to count a request against. Requests with an
+	// empty key are not limited. Defaults to `ByIP`.
+	Key func(ctx huma.Context) string
+
+	// Limit is the default limit for every operation. Operations can override
+	// it via `MetadataKey`. If unset, only operations with a limit in their
+	// metadata are limited.
+	Limit Limit
+}
+
+// ByIP keys requests by the client IP address of the connection. When
+// running behind a proxy, use `ByHeader` with e.g. `X-Real-IP` instead. The
+// address is read from contexts with a `RemoteAddr() string` method, which
+// all of the built-in adapters provide, unwrapping contexts wrapped by
+// middleware as needed. Requests with no known address are not limited.
+func ByIP(ctx huma.Context) string {
+	addr := ""
+	for ctx != nil {
+		if r, ok := ctx.(interface{ RemoteAddr() string }); ok {
+			addr = r.RemoteAddr()
+			break
+		}
+		u, ok := ctx.(interface{ Unwrap() huma.Context })
+		if !ok {
+			break
+		}
+		ctx = u.Unwrap()
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// ByHeader keys requests by the value of a request header, e.g. an API key.
+// Requests without the header are not limited.
+func ByHeader(name string) func(ctx huma.Context) string {
+	return func(ctx huma.Context) string {
+		return ctx.Header(name)
+	}
+}
+
+// ByContextValue keys requests by a string value in the request context,
+// e.g. the authenticated principal set by an auth middleware. Requests
+// without the value are not limited.
+func ByContextValue(key any) func(ctx huma.Context) string {
+	return func(ctx huma.Context) string {
+		v, _ := ctx.Context().Value(key).(string)
+		return v
+	}
+}
+
+// limitFor returns the limit for an operation, if it is limited.
+func limitFor(op *huma.Operation, def Limit) (Limit, bool) {
+	limit := def
+	if v, ok := op.Metadata[MetadataKey]; ok {
+		switch v := v.(type) {
+		case Limit:
+			limit = v
+		case bool:
+			if !v {
+				return Limit{}, false
+			}
+		default:
+			panic(fmt.Sprintf("ratelimit: invalid metadata for %s %s: %T", op.Method, op.Path, v))
+		}
+	}
+	if limit.Requests <= 0 || limit.Window <= 0 {
+		return Limit{}, false
+	}
+	return limit, true
+}
+
+// New creates a rate limit middleware for the given API. It also documents
+// the `429` response and rate limit headers for each limited operation
+// registered afterward.
+func New(api huma.API, opts Options) func(ctx huma.Context, next func(huma.Context)) {
+	if opts.Store == nil {
+		opts.Store = NewMemoryStore()
+	}
+	if opts.Key == nil {
+		opts.Key = ByIP
+	}
+
+	oapi := api.OpenAPI()
+	oapi.OnAddOperation = append(oapi.OnAddOperation, func(oapi *huma.OpenAPI, op *huma.Operation) {
+		if _, ok := limitFor(op, opts.Limit); ok {
+			document(oapi, op)
+		}
+	})
+
+	return func(ctx huma.Context, next func(huma.Context)) {
+		op := ctx.Operation()
+		limit, ok := limitFor(op, opts.Limit)
+		if !ok {
+			next(ctx)
+			return
+		}
+
+		key := opts.Key(ctx)
+		if key == "" {
+			next(ctx)
+			return
+		}
+		if _, ok := op.Metadata[MetadataKey]; ok {
+			// Operation-specific limits have their own quota.
+			key = op.Method + " " + op.Path + " " + key
+		}
+
+		res, err := opts.Store.Take(ctx.Context(), key, limit)
+		if err != nil {
+			// Fail open so a store outage does not take down the API.
+			huma.RequestLogger(api, ctx).Error("unable to check rate limit", "error", err)
+			next(ctx)
+			return
+		}
+
+		ctx.SetHeader(HeaderLimit, strconv.Itoa(limit.Requests))
+		ctx.SetHeader(HeaderRemaining, strconv.Itoa(res.Remaining))
+		ctx.SetHeader(HeaderReset, strconv.Itoa(ceilSeconds(res.Reset.Seconds())))
+		ctx.SetHeader(HeaderPolicy, fmt.Sprintf("%d;w=%d", limit.Requests, ceilSeconds(limit.Window.Seconds())))
+
+		if !res.Allowed {
+			ctx.SetHeader(HeaderRetryAfter, strconv.Itoa(ceilSeconds(res.RetryAfter.Seconds())))
+			huma.WriteErr(api, ctx, http.StatusTooManyRequests, "rate limit exceeded")
+			return
+		}
+
+		next(ctx)
+	}
+}
+
+// ceilSeconds rounds up to whole seconds, with a minimum of one, so clients
+// never retry too early.
+func ceilSeconds(s float64) int {
+	return int(math.Max(1, math.Ceil(s)))
+}
+
+// document adds the rate limit headers to the operation's responses and adds
+// a `429` response, if not already present.
+func document(oapi *huma.OpenAPI, op *huma.Operation) {
+	intHeader := func(desc string) *huma.Header {
+		return &huma.Header{
+			Description: desc,
+			Schema:      &huma.Schema{Type: huma.TypeInteger},
+		}
+	}
+	headers := map[string]*huma.Header{
+		HeaderLimit:     intHeader("Number of requests allowed in the window."),
+		HeaderRemaining: intHeader("Number of requests remaining in the window."),
+		HeaderReset:     intHeader("Seconds until the quota is fully restored."),
+		HeaderPolicy: {
+			Description: "Quota policy as `requests;w=window-seconds`.",
+			Schema:      &huma.Schema{Type: huma.TypeString},
+		},
+	}
+
+	// Responses may be shared with other operations, e.g. a PATCH generated
+	// by `autopatch`, so copy each one before modifying it.
+	status := strconv.Itoa(http.StatusTooManyRequests)
+	if op.Responses[status] == nil {
+		op.Responses[status] = huma.ErrorResponse(oapi.Components.Schemas, http.StatusTooManyRequests)
+	}
+	for code, resp := range op.Responses {
+		copied := *resp
+		copied.Headers = make(map[string]*huma.Param, len(resp.Headers)+len(headers)+1)
+		for name, h := range resp.Headers {
+			copied.Headers[name] = h
+		}
+		for name, h := range headers {
+			if _, ok := copied.Headers[name]; !ok {
+				copied.Headers[name] = h
+			}
+		}
+		if code == status {
+			copied.Headers[HeaderRetryAfter] = intHeader("Seconds to wait before retrying.")
+		}
+		op.Responses[code] = &copied
+	}
+}
@@ -0,0 +1,245 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type GreetingOutput struct {
+	Body struct {
+		Message string `json:"message"`
+	}
+}
+
+func register(api huma.API, id, path string, metadata map[string]any) {
+	huma.Register(api, huma.Operation{
+		OperationID: id,
+		Method:      http.MethodGet,
+		Path:        path,
+		Metadata:    metadata,
+	}, func(ctx context.Context, input *struct{}) (*GreetingOutput, error) {
+		resp := &GreetingOutput{}
+		resp.Body.Message = "Hello"
+		return resp, nil
+	})
+}
+
+func TestRateLimit(t *testing.T) {
+	// The default config marshals `application/problem+json` error bodies.
+	_, api := humatest.New(t, huma.DefaultConfig("Test API", "1.0.0"))
+	api.UseMiddleware(New(api, Options{
+		Limit: Limit{Requests: 2, Window: time.Minute},
+	}))
+	register(api, "default", "/default", nil)
+	register(api, "strict", "/strict", map[string]any{
+		MetadataKey: Limit{Requests: 1, Window: 10 * time.Second},
+	})
+	register(api, "unlimited", "/unlimited", map[string]any{
+		MetadataKey: false,
+	})
+
+	resp := api.Get("/default")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "2", resp.Header().Get(HeaderLimit))
+	assert.Equal(t, "1", resp.Header().Get(HeaderRemaining))
+	assert.Equal(t, "30", resp.Header().Get(HeaderReset))
+	assert.Equal(t, "2;w=60", resp.Header().Get(HeaderPolicy))
+	assert.Empty(t, resp.Header().Get(HeaderRetryAfter))
+
+	api.Get("/default")
+	resp = api.Get("/default")
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.Equal(t, "0", resp.Header().Get(HeaderRemaining))
+	assert.Equal(t, "30", resp.Header().Get(HeaderRetryAfter))
+	assert.Contains(t, resp.Body.String(), "rate limit exceeded")
+
+	// Operation limits have a separate quota.
+	resp = api.Get("/strict")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "1;w=10", resp.Header().Get(HeaderPolicy))
+	resp = api.Get("/strict")
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.Equal(t, "10", resp.Header().Get(HeaderRetryAfter))
+
+	for i := 0; i < 5; i++ {
+		resp = api.Get("/unlimited")
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Empty(t, resp.Header().Get(HeaderLimit))
+	}
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	_, api := humatest.New(t)
+	api.UseMiddleware(New(api, Options{
+		Key:   ByHeader("X-API-Key"),
+		Limit: Limit{Requests: 1, Window: time.Minute},
+	}))
+	register(api, "greet", "/greet", nil)
+
+	assert.Equal(t, http.StatusOK, api.Get("/greet", "X-API-Key: a").Code)
+	assert.Equal(t, http.StatusTooManyRequests, api.Get("/greet", "X-API-Key: a").Code)
+	assert.Equal(t, http.StatusOK, api.Get("/greet", "X-API-Key: b").Code)
+
+	// Requests without a key are not limited.
+	assert.Equal(t, http.StatusOK, api.Get("/greet").Code)
+	assert.Equal(t, http.StatusOK, api.Get("/greet").Code)
+}
+
+type principalKey struct{}
+
+func TestByContextValue(t *testing.T) {
+	_, api := humatest.New(t)
+	api.UseMiddleware(func(ctx huma.Context, next func(huma.Context)) {
+		next(huma.WithContext(ctx, context.WithValue(ctx.Context(), principalKey{}, ctx.Header("X-User"))))
+	})
+	api.UseMiddleware(New(api, Options{
+		Key:   ByContextValue(principalKey{}),
+		Limit: Limit{Requests: 1, Window: time.Minute},
+	}))
+	register(api, "greet", "/greet", nil)
+
+	assert.Equal(t, http.StatusOK, api.Get("/greet", "X-User: alice").Code)
+	assert.Equal(t, http.StatusTooManyRequests, api.Get("/greet", "X-User: alice").Code)
+	assert.Equal(t, http.StatusOK, api.Get("/greet", "X-User: bob").Code)
+}
+
+func TestByIP(t *testing.T) {
+	_, api := humatest.New(t)
+	var key string
+	api.UseMiddleware(func(ctx huma.Context, next func(huma.Context)) {
+		key = ByIP(ctx)
+		next(ctx)
+	})
+	register(api, "greet", "/greet", nil)
+
+	api.Get("/greet")
+	assert.Equal(t, "192.0.2.1", key)
+}
+
+func TestByIPWrapped(t *testing.T) {
+	_, api := humatest.New(t)
+	var key string
+	api.UseMiddleware(func(ctx huma.Context, next func(huma.Context)) {
+		next(huma.WithContext(ctx, ctx.Context()))
+	}, func(ctx huma.Context, next func(huma.Context)) {
+		// The remote address is found through contexts wrapped by middleware.
+		key = ByIP(ctx)
+		next(ctx)
+	})
+	register(api, "greet", "/greet", nil)
+
+	api.Get("/greet")
+	assert.Equal(t, "192.0.2.1", key)
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	return Result{}, errors.New("store unavailable")
+}
+
+func TestRateLimitStoreError(t *testing.T) {
+	buf := &bytes.Buffer{}
+	config := huma.DefaultConfig("Test API", "1.0.0")
+	config.Logger = slog.New(slog.NewTextHandler(buf, nil))
+	_, api := humatest.New(t, config)
+	api.UseMiddleware(New(api, Options{
+		Store: failingStore{},
+		Limit: Limit{Requests: 1, Window: time.Minute},
+	}))
+	register(api, "greet", "/greet", nil)
+
+	// Requests are allowed when the store fails.
+	resp := api.Get("/greet")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Empty(t, resp.Header().Get(HeaderLimit))
+	assert.Contains(t, buf.String(), "unable to check rate limit")
+	assert.Contains(t, buf.String(), "store unavailable")
+}
+
+func TestRateLimitOpenAPI(t *testing.T) {
+	_, api := humatest.New(t)
+	api.UseMiddleware(New(api, Options{
+		Limit: Limit{Requests: 1, Window: time.Minute},
+	}))
+	register(api, "limited", "/limited", nil)
+	register(api, "unlimited", "/unlimited", map[string]any{
+		MetadataKey: false,
+	})
+
+	limited := api.OpenAPI().Paths["/limited"].Get
+	require.Contains(t, limited.Responses, "429")
+	tooMany := limited.Responses["429"]
+	assert.Equal(t, "Too Many Requests", tooMany.Description)
+	require.Contains(t, tooMany.Content, "application/problem+json")
+	assert.Equal(t, "#/components/schemas/ErrorModel", tooMany.Content["application/problem+json"].Schema.Ref)
+	assert.Contains(t, tooMany.Headers, HeaderRetryAfter)
+
+	for _, status := range []string{"200", "429"} {
+		for _, name := range []string{HeaderLimit, HeaderRemaining, HeaderReset, HeaderPolicy} {
+			assert.Contains(t, limited.Responses[status].Headers, name)
+		}
+	}
+	assert.NotContains(t, limited.Responses["200"].Headers, HeaderRetryAfter)
+
+	unlimited := api.OpenAPI().Paths["/unlimited"].Get
+	assert.NotContains(t, unlimited.Responses, "429")
+	assert.NotContains(t, unlimited.Responses["200"].Headers, HeaderLimit)
+}
+
+func TestRateLimitOpenAPIShared(t *testing.T) {
+	orig := huma.Problems
+	huma.Problems = huma.NewProblemRegistry("/problems/")
+	defer func() { huma.Problems = orig }()
+	huma.Problems.Register(huma.ProblemType{
+		Name:   "slow-down",
+		Status: http.StatusTooManyRequests,
+		Extensions: &struct {
+			Quota int `json:"quota"`
+		}{},
+	})
+
+	_, api := humatest.New(t)
+	register(api, "shared", "/shared", nil)
+
+	// Responses shared with another operation must not be modified.
+	shared := api.OpenAPI().Paths["/shared"].Get.Responses["200"]
+	api.UseMiddleware(New(api, Options{
+		Limit: Limit{Requests: 1, Window: time.Minute},
+	}))
+	api.OpenAPI().AddOperation(&huma.Operation{
+		OperationID: "copy",
+		Method:      http.MethodPut,
+		Path:        "/shared",
+		Responses:   map[string]*huma.Response{"200": shared},
+	})
+	assert.Empty(t, shared.Headers)
+	assert.Contains(t, api.OpenAPI().Paths["/shared"].Put.Responses["200"].Headers, HeaderLimit)
+
+	// The 429 documents problem types like other error responses.
+	tooMany := api.OpenAPI().Paths["/shared"].Put.Responses["429"]
+	schema := tooMany.Content["application/problem+json"].Schema
+	require.Contains(t, schema.Extensions, "oneOf")
+	assert.Len(t, schema.Extensions["oneOf"], 2)
+}
+
+func TestInvalidMetadata(t *testing.T) {
+	_, api := humatest.New(t)
+	api.UseMiddleware(New(api, Options{}))
+
+	assert.Panics(t, func() {
+		register(api, "invalid", "/invalid", map[string]any{
+			MetadataKey: 5,
+		})
+	})
+}
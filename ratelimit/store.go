@@ -0,0 +1,210 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Algorithm determines how requests are counted against a limit.
+type Algorithm int
+
+const (
+	// TokenBucket allows bursts of up to `Requests` and refills at a steady
+	// rate of `Requests` per `Window`.
+	TokenBucket Algorithm = iota
+
+	// SlidingWindow allows up to `Requests` in any `Window`, approximated by
+	// weighting the previous fixed window's count by how much it overlaps.
+	SlidingWindow
+)
+
+// Limit is a rate limit policy.
+type Limit struct {
+	// Requests is the number of requests allowed per `Window`.
+	Requests int
+
+	// Window is the period over which requests are counted.
+	Window time.Duration
+
+	// Algorithm used to count requests. Defaults to `TokenBucket`.
+	Algorithm Algorithm
+}
+
+// Result describes the outcome of taking a request from a quota.
+type Result struct {
+	// Allowed is true if the request may proceed.
+	Allowed bool
+
+	// Remaining is the number of requests left in the current quota.
+	Remaining int
+
+	// Reset is the time until the quota is fully restored.
+	Reset time.Duration
+
+	// RetryAfter is the time until the next request will be allowed. It is
+	// only set when the request is not allowed.
+	RetryAfter time.Duration
+}
+
+// Store records requests for each key. Implementations must be safe for
+// concurrent use, e.g. an in-memory store for a single instance or a shared
+// store such as Redis for multiple instances.
+type Store interface {
+	// Take counts a request against the key's quota for the limit and
+	// returns whether it is allowed.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// bucket is the state for a single key. Token buckets use `tokens` and
+// `updated` while sliding windows use `start`, `prev`, and `curr`.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+
+	start time.Time
+	prev  int
+	curr  int
+
+	expires time.Time
+}
+
+// MemoryStore is an in-memory `Store` for a single instance. Idle keys are
+// removed periodically.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+	now     func() time.Time
+}
+
+// NewMemoryStore creates a new empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: map[string]*bucket{},
+		now:     time.Now,
+	}
+}
+
+// Take counts a request against the key's quota for the limit.
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now, limit.Window)
+
+	b := s.buckets[key]
+	if b == nil {
+		b = &bucket{tokens: float64(limit.Requests), updated: now, start: now}
+		s.buckets[key] = b
+	}
+
+	var res Result
+	if limit.Algorithm == SlidingWindow {
+		res = b.slidingWindow(now, limit)
+	} else {
+		res = b.tokenBucket(now, limit)
+	}
+	b.expires = now.Add(2 * limit.Window)
+	return res, nil
+}
+
+// sweep removes expired keys at most once per window.
+func (s *MemoryStore) sweep(now time.Time, window time.Duration) {
+	if now.Sub(s.swept) < window {
+		return
+	}
+	for k, b := range s.buckets {
+		if now.After(b.expires) {
+			delete(s.buckets, k)
+		}
+	}
+	s.swept = now
+}
+
+// tokenBucket refills tokens for the elapsed time and takes one if possible.
+func (b *bucket) tokenBucket(now time.Time, limit Limit) Result {
+	capacity := float64(limit.Requests)
+	rate := capacity / limit.Window.Seconds()
+
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+
+	res := Result{}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = seconds((capacity - b.tokens) / rate)
+	return res
+}
+
+// slidingWindow estimates the requests in the last window from the current
+// and previous fixed windows and counts the request if under the limit.
+func (b *bucket) slidingWindow(now time.Time, limit Limit) Result {
+	window := limit.Window
+	if elapsed := now.Sub(b.start); elapsed >= window {
+		// Advance to the fixed window containing now.
+		windows := elapsed / window
+		if windows == 1 {
+			b.prev = b.curr
+		} else {
+			b.prev = 0
+		}
+		b.curr = 0
+		b.start = b.start.Add(windows * window)
+	}
+
+	elapsed := now.Sub(b.start)
+	weight := 1 - elapsed.Seconds()/window.Seconds()
+	estimate := float64(b.prev)*weight + float64(b.curr)
+
+	res := Result{}
+	if estimate+1 <= float64(limit.Requests) {
+		b.curr++
+		estimate++
+		res.Allowed = true
+	} else {
+		res.RetryAfter = b.retryAfter(elapsed, limit)
+	}
+	res.Remaining = int(math.Max(0, float64(limit.Requests)-math.Ceil(estimate)))
+	// Requests in the current window stop counting one window after it ends.
+	res.Reset = 2*window - elapsed
+	if b.curr == 0 {
+		res.Reset = window - elapsed
+	}
+	return res
+}
+
+// retryAfter returns how long until the estimate drops enough to allow one
+// more request.
+func (b *bucket) retryAfter(elapsed time.Duration, limit Limit) time.Duration {
+	window := limit.Window.Seconds()
+	allowed := float64(limit.Requests - 1)
+
+	if b.curr <= limit.Requests-1 && b.prev > 0 {
+		// Wait for the previous window's weight to decay within this window.
+		t := window*(1-(allowed-float64(b.curr))/float64(b.prev)) - elapsed.Seconds()
+		if t >= 0 && elapsed.Seconds()+t < window {
+			return seconds(t)
+		}
+	}
+
+	// Wait for the next window, where the current count becomes the previous
+	// count and decays.
+	t := window - elapsed.Seconds()
+	if b.curr > 0 {
+		t += math.Max(0, window*(1-allowed/float64(b.curr)))
+	}
+	return seconds(t)
+}
+
+// seconds converts floating point seconds to a duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clock is a fake time source for the memory store.
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func newTestStore() (*MemoryStore, *clock) {
+	c := &clock{t: time.Unix(1700000000, 0)}
+	s := NewMemoryStore()
+	s.now = c.now
+	return s, c
+}
+
+func take(t *testing.T, s *MemoryStore, key string, limit Limit) Result {
+	t.Helper()
+	res, err := s.Take(context.Background(), key, limit)
+	require.NoError(t, err)
+	return res
+}
+
+func TestTokenBucket(t *testing.T) {
+	s, c := newTestStore()
+	limit := Limit{Requests: 3, Window: 3 * time.Second}
+
+	// Bursts up to the limit are allowed.
+	for i := 2; i >= 0; i-- {
+		res := take(t, s, "a", limit)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, i, res.Remaining)
+	}
+
+	res := take(t, s, "a", limit)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	assert.Equal(t, time.Second, res.RetryAfter)
+	assert.Equal(t, 3*time.Second, res.Reset)
+
+	// Other keys have their own quota.
+	assert.True(t, take(t, s, "b", limit).Allowed)
+
+	// Tokens refill at a steady rate.
+	c.advance(time.Second)
+	res = take(t, s, "a", limit)
+	assert.True(t, res.Allowed)
+	assert.False(t, take(t, s, "a", limit).Allowed)
+
+	// Refills never exceed the capacity.
+	c.advance(time.Hour)
+	res = take(t, s, "a", limit)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 2, res.Remaining)
+}
+
+func TestSlidingWindow(t *testing.T) {
+	s, c := newTestStore()
+	limit := Limit{Requests: 4, Window: 10 * time.Second, Algorithm: SlidingWindow}
+
+	for i := 3; i >= 0; i-- {
+		res := take(t, s, "a", limit)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, i, res.Remaining)
+	}
+
+	res := take(t, s, "a", limit)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 20*time.Second, res.Reset)
+	// The next window starts in 10s, then the previous count must decay from
+	// 4 to 3 which takes another 2.5s.
+	assert.Equal(t, 12500*time.Millisecond, res.RetryAfter)
+
+	// Halfway through the next window, half of the previous requests count.
+	c.advance(15 * time.Second)
+	res = take(t, s, "a", limit)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 1, res.Remaining)
+	assert.True(t, take(t, s, "a", limit).Allowed)
+	res = take(t, s, "a", limit)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 2500*time.Millisecond, res.RetryAfter)
+
+	c.advance(res.RetryAfter)
+	assert.True(t, take(t, s, "a", limit).Allowed)
+
+	// After two idle windows, the full quota is available.
+	c.advance(20 * time.Second)
+	res = take(t, s, "a", limit)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 3, res.Remaining)
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	s, c := newTestStore()
+	limit := Limit{Requests: 1, Window: time.Second}
+
+	take(t, s, "a", limit)
+	take(t, s, "b", limit)
+	assert.Len(t, s.buckets, 2)
+
+	c.advance(time.Minute)
+	take(t, s, "c", limit)
+	assert.Len(t, s.buckets, 1)
+	assert.Contains(t, s.buckets, "c")
+}
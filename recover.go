@@ -0,0 +1,61 @@
+package huma
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// OnPanic is called when a panic is recovered while handling a request for
+// an operation registered via `huma.Register`, e.g. in a handler, resolver,
+// or transformer. The error wraps the recovered value and the stack is from
+// where the panic occurred. Set it at service startup to report panics to an
+// error tracking service:
+//
+//	huma.OnPanic = func(ctx huma.Context, err error, stack []byte) {
+//		sentry.CaptureException(err)
+//	}
+//
+// The panic is always logged via the API's `Logger` and the client receives
+// a `500 Internal Server Error` response.
+var OnPanic func(ctx Context, err error, stack []byte)
+
+// recoverPanic recovers from a panic while handling a request, logs it,
+// reports it via `OnPanic`, and writes an error response. The
+// `http.ErrAbortHandler` sentinel is re-panicked so the server aborts the
+// response as usual. It must be called directly via `defer`.
+func recoverPanic(api API, ctx Context, ph *phases) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	stack := debug.Stack()
+	if hp, ok := r.(*handlerPanic); ok {
+		// Panic from a handler running with a timeout.
+		r, stack = hp.value, hp.stack
+	}
+	if r == http.ErrAbortHandler {
+		panic(r)
+	}
+	var err error
+	if e, ok := r.(error); ok {
+		err = fmt.Errorf("panic: %w", e)
+	} else {
+		err = fmt.Errorf("panic: %v", r)
+	}
+
+	ph.done(err)
+	RequestLogger(api, ctx).Error("panic recovered", "error", err, "stack", string(stack))
+	if OnPanic != nil {
+		OnPanic(ctx, err, stack)
+	}
+
+	// Writing the error may panic again, e.g. if a transformer is the cause.
+	defer func() {
+		if r := recover(); r != nil {
+			RequestLogger(api, ctx).Error("unable to write panic response", "error", fmt.Sprint(r))
+		}
+	}()
+	WriteErr(api, ctx, http.StatusInternalServerError, "internal server error")
+}
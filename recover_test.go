@@ -0,0 +1,111 @@
+package huma
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type panicResolver struct{}
+
+func (r *panicResolver) Resolve(ctx Context) []error {
+	panic(errors.New("resolver failed"))
+}
+
+func TestRecoverPanic(t *testing.T) {
+	logs := &bytes.Buffer{}
+	config := DefaultConfig("Test API", "1.0.0")
+	config.Logger = slog.New(slog.NewTextHandler(logs, nil))
+	config.Transformers = append(config.Transformers, func(ctx Context, status string, v any) (any, error) {
+		if ctx.Operation().OperationID == "transformer" {
+			panic("transformer failed")
+		}
+		return v, nil
+	})
+	r := chi.NewRouter()
+	api := NewTestAdapter(r, config)
+
+	reported := []string{}
+	OnPanic = func(ctx Context, err error, stack []byte) {
+		reported = append(reported, ctx.Operation().OperationID+": "+err.Error())
+		assert.Contains(t, string(stack), "recover_test.go")
+	}
+	defer func() { OnPanic = nil }()
+
+	Register(api, Operation{
+		OperationID: "handler",
+		Method:      http.MethodGet,
+		Path:        "/handler",
+	}, func(ctx context.Context, input *struct{}) (*struct{}, error) {
+		panic("handler failed")
+	})
+
+	Register(api, Operation{
+		OperationID: "resolver",
+		Method:      http.MethodGet,
+		Path:        "/resolver",
+	}, func(ctx context.Context, input *struct{ panicResolver }) (*struct{}, error) {
+		return nil, nil
+	})
+
+	Register(api, Operation{
+		OperationID: "transformer",
+		Method:      http.MethodGet,
+		Path:        "/transformer",
+	}, func(ctx context.Context, input *struct{}) (*struct{ Body string }, error) {
+		return &struct{ Body string }{Body: "hello"}, nil
+	})
+
+	Register(api, Operation{
+		OperationID: "abort",
+		Method:      http.MethodGet,
+		Path:        "/abort",
+	}, func(ctx context.Context, input *struct{}) (*struct{}, error) {
+		panic(http.ErrAbortHandler)
+	})
+
+	do := func(path string, headers ...string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		for i := 0; i < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("/handler")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"$schema": "https:///schemas/ErrorModel.json", "title": "Internal Server Error", "status": 500, "detail": "internal server error"}`, w.Body.String())
+	assert.Contains(t, logs.String(), `level=ERROR msg="panic recovered" operationId=handler method=GET path=/handler error="panic: handler failed" stack=`)
+
+	// The error is negotiated like any other response.
+	w = do("/resolver", "Accept", "application/cbor")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, "application/problem+cbor", w.Header().Get("Content-Type"))
+
+	// Panics after the status is sent are still recovered & reported.
+	w = do("/transformer")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, logs.String(), `msg="unable to write panic response" operationId=transformer`)
+
+	assert.Equal(t, []string{
+		"handler: panic: handler failed",
+		"resolver: panic: resolver failed",
+		"transformer: panic: transformer failed",
+	}, reported)
+
+	// Aborting the handler is passed through to the server.
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		do("/abort")
+	})
+	assert.Len(t, reported, 3)
+}
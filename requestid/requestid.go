@@ -0,0 +1,133 @@
+// Package requestid provides middleware which gives every request an ID for
+// correlating client-reported errors with logs. The ID is read from the
+// request headers or generated, passed to the handler via its context,
+// echoed in the response headers, and included in error responses.
+//
+//	config := huma.DefaultConfig("My API", "1.0.0")
+//	config.Transformers = append([]huma.Transformer{requestid.Transformer}, config.Transformers...)
+//	api := humachi.New(router, config)
+//	api.UseMiddleware(requestid.New(api, requestid.Options{}))
+package requestid
+
+import (
+	"context"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/google/uuid"
+)
+
+// MaxLength is the maximum length of a request ID read from the request
+// headers. Longer IDs are replaced with a generated one.
+var MaxLength = 128
+
+// ExtensionKey is the error model extension member set by `Transformer`.
+const ExtensionKey = "requestId"
+
+// Options configures the request ID middleware.
+type Options struct {
+	// Headers to read an incoming request ID from, in order of preference.
+	// For `traceparent` the trace ID is used. Defaults to `X-Request-ID`.
+	Headers []string
+
+	// ResponseHeader echoes the request ID to the client. Defaults to
+	// `X-Request-ID`.
+	ResponseHeader string
+
+	// Generate creates a new request ID when none is sent by the client.
+	// Defaults to a random UUID.
+	Generate func() string
+}
+
+type contextKey struct{}
+
+// Get returns the request ID from the context, or an empty string if there
+// is none.
+func Get(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// valid returns true if the ID is safe to log and echo back to the client.
+func valid(id string) bool {
+	if id == "" || len(id) > MaxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// fromHeader returns the request ID from the header value, if valid.
+func fromHeader(name, value string) string {
+	if strings.EqualFold(name, "traceparent") {
+		// version-traceid-parentid-flags
+		parts := strings.Split(value, "-")
+		if len(parts) != 4 || len(parts[1]) != 32 || strings.Trim(parts[1], "0") == "" {
+			return ""
+		}
+		value = parts[1]
+	}
+	if !valid(value) {
+		return ""
+	}
+	return value
+}
+
+// New creates a new request ID middleware for the given API. The request ID
+// is also added to messages logged via `huma.RequestLogger`.
+func New(api huma.API, opts Options) func(ctx huma.Context, next func(huma.Context)) {
+	if len(opts.Headers) == 0 {
+		opts.Headers = []string{"X-Request-ID"}
+	}
+	if opts.ResponseHeader == "" {
+		opts.ResponseHeader = "X-Request-ID"
+	}
+	if opts.Generate == nil {
+		opts.Generate = uuid.NewString
+	}
+
+	return func(ctx huma.Context, next func(huma.Context)) {
+		id := ""
+		for _, name := range opts.Headers {
+			if id = fromHeader(name, ctx.Header(name)); id != "" {
+				break
+			}
+		}
+		if id == "" {
+			id = opts.Generate()
+		}
+
+		ctx.SetHeader(opts.ResponseHeader, id)
+		c := context.WithValue(ctx.Context(), contextKey{}, id)
+		c = huma.WithLogAttrs(c, "requestId", id)
+		next(huma.WithContext(ctx, c))
+	}
+}
+
+// Transformer adds the request ID to error responses as the `requestId`
+// extension member so clients can report it. Add it to the start of the
+// API's `config.Transformers` so it runs before the default transformer which
+// adds the `$schema` link.
+func Transformer(ctx huma.Context, status string, v any) (any, error) {
+	e, ok := v.(*huma.ErrorModel)
+	if !ok {
+		return v, nil
+	}
+	id := Get(ctx.Context())
+	if id == "" {
+		return v, nil
+	}
+
+	// Copy the error so shared error values are never modified.
+	copied := *e
+	copied.Extensions = make(map[string]any, len(e.Extensions)+1)
+	for k, v := range e.Extensions {
+		copied.Extensions[k] = v
+	}
+	copied.Extensions[ExtensionKey] = id
+	return &copied, nil
+}
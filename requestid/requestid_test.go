@@ -0,0 +1,116 @@
+package requestid
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+var errShared = huma.Error404NotFound("thing not found")
+
+func setup(t *testing.T, opts Options) (humatest.TestAPI, *bytes.Buffer) {
+	logs := &bytes.Buffer{}
+	config := huma.DefaultConfig("Test API", "1.0.0")
+	config.Transformers = append([]huma.Transformer{Transformer}, config.Transformers...)
+	config.Logger = slog.New(slog.NewTextHandler(logs, nil))
+	_, api := humatest.New(t, config)
+	api.UseMiddleware(New(api, opts))
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-id",
+		Method:      http.MethodGet,
+		Path:        "/id",
+	}, func(ctx context.Context, input *struct{}) (*struct{ Body string }, error) {
+		return &struct{ Body string }{Body: Get(ctx)}, nil
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-missing",
+		Method:      http.MethodGet,
+		Path:        "/missing",
+	}, func(ctx context.Context, input *struct{}) (*struct{}, error) {
+		return nil, errShared
+	})
+
+	huma.Register(api, huma.Operation{
+		OperationID: "get-panic",
+		Method:      http.MethodGet,
+		Path:        "/panic",
+	}, func(ctx context.Context, input *struct{}) (*struct{}, error) {
+		panic("boom")
+	})
+
+	return api, logs
+}
+
+func TestRequestID(t *testing.T) {
+	api, _ := setup(t, Options{})
+
+	// Generated when missing.
+	resp := api.Get("/id")
+	id := resp.Header().Get("X-Request-ID")
+	_, err := uuid.Parse(id)
+	assert.NoError(t, err)
+	assert.Equal(t, `"`+id+`"`, strings.TrimSpace(resp.Body.String()))
+
+	// Read from the request.
+	resp = api.Get("/id", "X-Request-ID: abc-123")
+	assert.Equal(t, "abc-123", resp.Header().Get("X-Request-ID"))
+	assert.Equal(t, `"abc-123"`, strings.TrimSpace(resp.Body.String()))
+
+	// Unsafe or overly long IDs are replaced.
+	for _, bad := range []string{"has spaces", strings.Repeat("a", MaxLength+1), "newé"} {
+		resp = api.Get("/id", "X-Request-ID: "+bad)
+		assert.NotEqual(t, bad, resp.Header().Get("X-Request-ID"))
+		assert.NotEmpty(t, resp.Header().Get("X-Request-ID"))
+	}
+}
+
+func TestRequestIDOptions(t *testing.T) {
+	api, _ := setup(t, Options{
+		Headers:        []string{"traceparent", "X-Correlation-ID"},
+		ResponseHeader: "X-Correlation-ID",
+		Generate:       func() string { return "generated" },
+	})
+
+	resp := api.Get("/id", "traceparent: 00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", "X-Correlation-ID: other")
+	assert.Equal(t, "0af7651916cd43dd8448eb211c80319c", resp.Header().Get("X-Correlation-ID"))
+
+	// Invalid trace context falls back to the next header.
+	resp = api.Get("/id", "traceparent: 00-00000000000000000000000000000000-b7ad6b7169203331-01", "X-Correlation-ID: other")
+	assert.Equal(t, "other", resp.Header().Get("X-Correlation-ID"))
+
+	resp = api.Get("/id")
+	assert.Equal(t, "generated", resp.Header().Get("X-Correlation-ID"))
+	assert.Empty(t, resp.Header().Get("X-Request-ID"))
+}
+
+func TestRequestIDErrors(t *testing.T) {
+	api, logs := setup(t, Options{})
+
+	resp := api.Get("/missing", "X-Request-ID: abc-123")
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	var body map[string]any
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	assert.Equal(t, "abc-123", body["requestId"])
+	assert.Equal(t, "thing not found", body["detail"])
+
+	// Shared error values are not modified.
+	assert.Nil(t, errShared.(*huma.ErrorModel).Extensions)
+
+	// Logs are correlated with the request ID.
+	resp = api.Get("/panic", "X-Request-ID: def-456")
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.Contains(t, resp.Body.String(), `"requestId":"def-456"`)
+	assert.Contains(t, logs.String(), `msg="panic recovered" operationId=get-panic method=GET path=/panic requestId=def-456`)
+}
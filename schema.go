@@ -93,73 +93,109 @@ type Schema struct {
 	propertyNames []string        `yaml:"-"`
 
 	// Precomputed validation messages. These prevent allocations during
-	// validation and are known at schema creation time.
-	msgEnum             string            `yaml:"-"`
-	msgMinimum          string            `yaml:"-"`
-	msgExclusiveMinimum string            `yaml:"-"`
-	msgMaximum          string            `yaml:"-"`
-	msgExclusiveMaximum string            `yaml:"-"`
-	msgMultipleOf       string            `yaml:"-"`
-	msgMinLength        string            `yaml:"-"`
-	msgMaxLength        string            `yaml:"-"`
-	msgPattern          string            `yaml:"-"`
-	msgMinItems         string            `yaml:"-"`
-	msgMaxItems         string            `yaml:"-"`
-	msgMinProperties    string            `yaml:"-"`
-	msgMaxProperties    string            `yaml:"-"`
-	msgRequired         map[string]string `yaml:"-"`
+	// validation and are known at schema creation time. There is one set of
+	// messages per registered message catalog locale.
+	msgs       schemaMessages             `yaml:"-"`
+	localeMsgs map[string]*schemaMessages `yaml:"-"`
+}
+
+// schemaMessages holds the precomputed validation messages for a schema in
+// a single locale.
+type schemaMessages struct {
+	enum             string
+	minimum          string
+	exclusiveMinimum string
+	maximum          string
+	exclusiveMaximum string
+	multipleOf       string
+	minLength        string
+	maxLength        string
+	pattern          string
+	minItems         string
+	maxItems         string
+	minProperties    string
+	maxProperties    string
+	required         map[string]string
+}
+
+// messages returns the precomputed messages for the given locale, falling
+// back to the default messages.
+func (s *Schema) messages(locale string) *schemaMessages {
+	if locale != "" {
+		if m := s.localeMsgs[locale]; m != nil {
+			return m
+		}
+	}
+	return &s.msgs
 }
 
 // PrecomputeMessages tries to precompute as many validation error messages
 // as possible so that new strings aren't allocated during request validation.
+// Messages are computed for the default locale as well as for every message
+// catalog registered via `huma.RegisterMessageCatalog`.
 func (s *Schema) PrecomputeMessages() {
-	s.msgEnum = "expected value to be one of \"" + strings.Join(mapTo(s.Enum, func(v any) string {
+	if s.Pattern != "" {
+		s.patternRe = regexp.MustCompile(s.Pattern)
+	}
+
+	s.precomputeLocale(&s.msgs, nil)
+	for _, locale := range catalogLocales {
+		if s.localeMsgs == nil {
+			s.localeMsgs = map[string]*schemaMessages{}
+		}
+		m := &schemaMessages{}
+		s.precomputeLocale(m, catalogs[locale])
+		s.localeMsgs[locale] = m
+	}
+}
+
+func (s *Schema) precomputeLocale(m *schemaMessages, c *MessageCatalog) {
+	m.enum = fmt.Sprintf(c.Get(MsgExpectedOneOf), strings.Join(mapTo(s.Enum, func(v any) string {
 		return fmt.Sprintf("%v", v)
-	}), ", ") + "\""
+	}), ", "))
 	if s.Minimum != nil {
-		s.msgMinimum = fmt.Sprintf("expected number >= %v", *s.Minimum)
+		m.minimum = fmt.Sprintf(c.Get(MsgExpectedMinimum), *s.Minimum)
 	}
 	if s.ExclusiveMinimum != nil {
-		s.msgExclusiveMinimum = fmt.Sprintf("expected number > %v", *s.ExclusiveMinimum)
+		m.exclusiveMinimum = fmt.Sprintf(c.Get(MsgExpectedExclusiveMinimum), *s.ExclusiveMinimum)
 	}
 	if s.Maximum != nil {
-		s.msgMaximum = fmt.Sprintf("expected number <= %v", *s.Maximum)
+		m.maximum = fmt.Sprintf(c.Get(MsgExpectedMaximum), *s.Maximum)
 	}
 	if s.ExclusiveMaximum != nil {
-		s.msgExclusiveMaximum = fmt.Sprintf("expected number < %v", *s.ExclusiveMaximum)
+		m.exclusiveMaximum = fmt.Sprintf(c.Get(MsgExpectedExclusiveMaximum), *s.ExclusiveMaximum)
 	}
 	if s.MultipleOf != nil {
-		s.msgMultipleOf = fmt.Sprintf("expected number to be a multiple of %v", *s.MultipleOf)
+		m.multipleOf = fmt.Sprintf(c.Get(MsgExpectedMultipleOf), *s.MultipleOf)
 	}
 	if s.MinLength != nil {
-		s.msgMinLength = fmt.Sprintf("expected length >= %d", *s.MinLength)
+		m.minLength = fmt.Sprintf(c.Get(MsgExpectedMinLength), *s.MinLength)
 	}
 	if s.MaxLength != nil {
-		s.msgMaxLength = fmt.Sprintf("expected length <= %d", *s.MaxLength)
+		m.maxLength = fmt.Sprintf(c.Get(MsgExpectedMaxLength), *s.MaxLength)
 	}
 	if s.Pattern != "" {
-		s.patternRe = regexp.MustCompile(s.Pattern)
-		s.msgPattern = "expected string to match pattern " + s.Pattern
+		m.pattern = fmt.Sprintf(c.Get(MsgExpectedPattern), s.Pattern)
 	}
 	if s.MinItems != nil {
-		s.msgMinItems = fmt.Sprintf("expected array length >= %d", *s.MinItems)
+		m.minItems = fmt.Sprintf(c.Get(MsgExpectedMinItems), *s.MinItems)
 	}
 	if s.MaxItems != nil {
-		s.msgMaxItems = fmt.Sprintf("expected array length <= %d", *s.MaxItems)
+		m.maxItems = fmt.Sprintf(c.Get(MsgExpectedMaxItems), *s.MaxItems)
 	}
 	if s.MinProperties != nil {
-		s.msgMinProperties = fmt.Sprintf("expected object with at least %d properties", *s.MinProperties)
+		m.minProperties = fmt.Sprintf(c.Get(MsgExpectedMinProperties), *s.MinProperties)
 	}
 	if s.MaxProperties != nil {
-		s.msgMaxProperties = fmt.Sprintf("expected object with at most %d properties", *s.MaxProperties)
+		m.maxProperties = fmt.Sprintf(c.Get(MsgExpectedMaxProperties), *s.MaxProperties)
 	}
 
 	if s.Required != nil {
-		if s.msgRequired == nil {
-			s.msgRequired = map[string]string{}
+		if m.required == nil {
+			m.required = map[string]string{}
 		}
 		for _, name := range s.Required {
-			s.msgRequired[name] = "expected required property " + name + " to be present"
+			m.required[name] = fmt.Sprintf(c.Get(MsgExpectedRequired), name)
 		}
 	}
 }
@@ -0,0 +1,278 @@
+package sse
+
+import (
+	"bufio"
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrDecode is returned when an event's data cannot be decoded into the Go
+// type registered for the event.
+var ErrDecode = errors.New("sse: unable to decode event")
+
+// DefaultRetry is the default time to wait before reconnecting if the server
+// has not sent a `retry` field.
+var DefaultRetry = 3 * time.Second
+
+// Event is a single event received from a server.
+type Event struct {
+	// ID is the event ID, or zero if none was sent.
+	ID int
+
+	// Name is the event name, which defaults to `message`.
+	Name string
+
+	// Data is the decoded event data. If the event name is in the event type
+	// map then this is a value of the mapped Go type, otherwise it is the raw
+	// `json.RawMessage`.
+	Data any
+
+	// Retry is the reconnection time in milliseconds, or zero if none was sent.
+	Retry int
+}
+
+// Unmarshalers decode event data for content types other than JSON and
+// `text/plain`, keyed by content type without parameters. You may add entries
+// at service startup to match the API's `Formats`, e.g. `application/yaml`.
+var Unmarshalers = map[string]func(data []byte, v any) error{}
+
+// decode converts raw event data into the Go type registered for the event,
+// using the event's content type from `contentTypes`.
+func decode(eventTypeMap map[string]any, contentTypes map[string]string, name string, data []byte) (any, error) {
+	key := name
+	v, ok := eventTypeMap[key]
+	if !ok && name == "message" {
+		key = ""
+		v, ok = eventTypeMap[key]
+	}
+	if !ok {
+		return json.RawMessage(data), nil
+	}
+	ptr := reflect.New(deref(reflect.TypeOf(v)))
+
+	var err error
+	ct := mediaType(contentTypes[key])
+	switch {
+	case isJSON(ct):
+		err = json.Unmarshal(data, ptr.Interface())
+	case ct == "text/plain":
+		err = decodeText(data, ptr)
+	case Unmarshalers[ct] != nil:
+		err = Unmarshalers[ct](data, ptr.Interface())
+	default:
+		err = fmt.Errorf("no unmarshaler for content type %s", ct)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w %s: %w", ErrDecode, name, err)
+	}
+	return ptr.Elem().Interface(), nil
+}
+
+// decodeText decodes `text/plain` event data into the value pointed to by
+// `ptr`, reversing how the server encodes text events.
+func decodeText(data []byte, ptr reflect.Value) error {
+	if u, ok := ptr.Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText(data)
+	}
+	v := ptr.Elem()
+	switch {
+	case v.Kind() == reflect.String:
+		v.SetString(string(data))
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		v.SetBytes(append([]byte{}, data...))
+	default:
+		_, err := fmt.Sscan(string(data), ptr.Interface())
+		return err
+	}
+	return nil
+}
+
+// Read parses a `text/event-stream` body, decoding each event's data using
+// the same event type map passed to `Register` and calling `cb` for each
+// event. Reading stops when the body ends or `cb` returns an error. Pass the
+// `Options` given to `Register` to decode events using their `ContentTypes`.
+func Read(r io.Reader, eventTypeMap map[string]any, cb func(Event) error, options ...Options) error {
+	var opts Options
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	reader := bufio.NewReader(r)
+
+	event := Event{Name: "message"}
+	var data strings.Builder
+	hasData := false
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		if line == "" {
+			// Blank line dispatches the event.
+			if hasData {
+				event.Data, err = decode(eventTypeMap, opts.ContentTypes, event.Name, []byte(data.String()))
+				if err != nil {
+					return err
+				}
+				if err := cb(event); err != nil {
+					return err
+				}
+			}
+			event = Event{Name: "message"}
+			data.Reset()
+			hasData = false
+			continue
+		}
+
+		if strings.HasPrefix(line, ":") {
+			// Comment, e.g. a heartbeat.
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			event.ID, _ = strconv.Atoi(value)
+		case "event":
+			event.Name = value
+		case "retry":
+			event.Retry, _ = strconv.Atoi(value)
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		}
+	}
+}
+
+// ReadAll reads all events from a `text/event-stream` body. This is useful
+// in tests, e.g. with the response body from `humatest.TestAPI`:
+//
+//	resp := api.Get("/events")
+//	events, err := sse.ReadAll(resp.Body, eventTypeMap)
+func ReadAll(r io.Reader, eventTypeMap map[string]any, options ...Options) ([]Event, error) {
+	events := []Event{}
+	err := Read(r, eventTypeMap, func(e Event) error {
+		events = append(events, e)
+		return nil
+	}, options...)
+	return events, err
+}
+
+// Client consumes SSE streams, automatically reconnecting and resuming from
+// the last received event ID via the `Last-Event-ID` header.
+type Client struct {
+	// EventTypes maps event names to Go types, the same as `Register`.
+	EventTypes map[string]any
+
+	// ContentTypes maps event names to the content type of their data, the
+	// same as `Options.ContentTypes`. Events not in this map are JSON.
+	ContentTypes map[string]string
+
+	// HTTPClient is used to make requests. Defaults to `http.DefaultClient`.
+	HTTPClient *http.Client
+
+	// Handler, if set, serves requests in-process instead of making network
+	// requests, e.g. `api.Adapter()` from a `humatest.TestAPI`. The response
+	// is fully buffered so this is only suitable for streams which end.
+	Handler http.Handler
+
+	// MaxReconnects is the maximum number of times to reconnect after the
+	// stream ends or fails. Zero disables reconnection and a negative value
+	// reconnects forever.
+	MaxReconnects int
+}
+
+// do makes a single request.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.Handler != nil {
+		w := httptest.NewRecorder()
+		c.Handler.ServeHTTP(w, req)
+		return w.Result(), nil
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+// Stream makes the request and calls `cb` for each received event until the
+// context is done, `cb` returns an error, or the stream ends and cannot be
+// reconnected. The request must not have a body.
+func (c *Client) Stream(ctx context.Context, req *http.Request, cb func(Event) error) error {
+	retry := DefaultRetry
+	lastID := 0
+	reconnects := 0
+
+	for {
+		r := req.Clone(ctx)
+		r.Header.Set("Accept", "text/event-stream")
+		if lastID > 0 {
+			r.Header.Set("Last-Event-ID", strconv.Itoa(lastID))
+		}
+
+		resp, err := c.do(r)
+		if err == nil {
+			if resp.StatusCode != http.StatusOK {
+				resp.Body.Close()
+				return fmt.Errorf("sse: unexpected status %d", resp.StatusCode)
+			}
+			if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+				resp.Body.Close()
+				return fmt.Errorf("sse: unexpected content type %q", ct)
+			}
+
+			var cbErr error
+			err = Read(resp.Body, c.EventTypes, func(e Event) error {
+				if e.ID > 0 {
+					lastID = e.ID
+				}
+				if e.Retry > 0 {
+					retry = time.Duration(e.Retry) * time.Millisecond
+				}
+				cbErr = cb(e)
+				return cbErr
+			}, Options{ContentTypes: c.ContentTypes})
+			resp.Body.Close()
+			if cbErr != nil {
+				return cbErr
+			}
+			if errors.Is(err, ErrDecode) {
+				return err
+			}
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if c.MaxReconnects >= 0 && reconnects >= c.MaxReconnects {
+			return err
+		}
+		reconnects++
+
+		select {
+		case <-time.After(retry):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
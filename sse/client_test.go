@@ -0,0 +1,142 @@
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/stretchr/testify/assert"
+)
+
+var clientEvents = map[string]any{
+	"message":    DefaultMessage{},
+	"userCreate": &UserCreatedEvent{},
+}
+
+func TestRead(t *testing.T) {
+	body := strings.NewReader(": comment\n" +
+		"id: 5\r\n" +
+		"retry: 1000\n" +
+		"data: {\"message\":\n" +
+		"data: \"hi\"}\n" +
+		"\n" +
+		"event: userCreate\n" +
+		"data:{\"user_id\":1,\"username\":\"foo\"}\n" +
+		"\n" +
+		"event: unknown\n" +
+		"data: [1, 2]\n" +
+		"\n" +
+		"id: 6\n" +
+		"\n" +
+		"data: {\"message\":\"no trailing newline\"}\n")
+
+	events, err := ReadAll(body, clientEvents)
+	assert.NoError(t, err)
+	assert.Equal(t, []Event{
+		{ID: 5, Name: "message", Retry: 1000, Data: DefaultMessage{Message: "hi"}},
+		{Name: "userCreate", Data: UserCreatedEvent{UserID: 1, Username: "foo"}},
+		{Name: "unknown", Data: json.RawMessage("[1, 2]")},
+	}, events)
+
+	_, err = ReadAll(strings.NewReader("data: bad\n\n"), clientEvents)
+	assert.ErrorIs(t, err, ErrDecode)
+
+	// Callback errors stop reading.
+	stop := errors.New("stop")
+	err = Read(strings.NewReader("data: {}\n\ndata: {}\n\n"), clientEvents, func(e Event) error {
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+}
+
+func TestClient(t *testing.T) {
+	_, api := humatest.New(t)
+
+	lastIDs := []string{}
+	Register(api, huma.Operation{
+		OperationID: "client",
+		Method:      http.MethodGet,
+		Path:        "/client",
+	}, clientEvents, func(ctx context.Context, input *struct {
+		LastEventID string `header:"Last-Event-ID"`
+	}, send Sender) {
+		lastIDs = append(lastIDs, input.LastEventID)
+		send(Message{Retry: 1, Data: DefaultMessage{Message: "hello"}})
+		send.Data(UserCreatedEvent{UserID: 1, Username: "foo"})
+	}, Options{Store: NewMemoryStore(10), StreamID: func(ctx huma.Context) string {
+		return "client"
+	}})
+
+	// Round-trip through the test API.
+	events, err := ReadAll(api.Get("/client").Body, clientEvents)
+	assert.NoError(t, err)
+	assert.Equal(t, []Event{
+		{ID: 1, Name: "message", Retry: 1, Data: DefaultMessage{Message: "hello"}},
+		{ID: 2, Name: "userCreate", Data: UserCreatedEvent{UserID: 1, Username: "foo"}},
+	}, events)
+
+	// Reconnect and resume from the last event ID.
+	client := &Client{
+		EventTypes:    clientEvents,
+		Handler:       api.Adapter(),
+		MaxReconnects: 1,
+	}
+	req, _ := http.NewRequest(http.MethodGet, "/client", nil)
+	ids := []int{}
+	err = client.Stream(context.Background(), req, func(e Event) error {
+		ids = append(ids, e.ID)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{3, 4, 5, 6}, ids)
+	assert.Equal(t, []string{"", "", "4"}, lastIDs)
+
+	// Callback errors stop the stream.
+	stop := errors.New("stop")
+	err = client.Stream(context.Background(), req, func(e Event) error {
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+
+	// Unexpected responses are not retried.
+	req, _ = http.NewRequest(http.MethodGet, "/missing", nil)
+	err = client.Stream(context.Background(), req, func(e Event) error {
+		return nil
+	})
+	assert.EqualError(t, err, "sse: unexpected status 404")
+}
+
+type Greeting string
+
+func TestClientContentTypes(t *testing.T) {
+	_, api := humatest.New(t)
+
+	eventTypes := map[string]any{"greeting": Greeting("")}
+	contentTypes := map[string]string{"greeting": "text/plain"}
+	Register(api, huma.Operation{
+		OperationID: "greetings",
+		Method:      http.MethodGet,
+		Path:        "/greetings",
+	}, eventTypes, func(ctx context.Context, input *struct{}, send Sender) {
+		send.Data(Greeting("hello, world"))
+	}, Options{ContentTypes: contentTypes})
+
+	client := &Client{
+		EventTypes:   eventTypes,
+		ContentTypes: contentTypes,
+		Handler:      api.Adapter(),
+	}
+	req, _ := http.NewRequest(http.MethodGet, "/greetings", nil)
+	received := []any{}
+	err := client.Stream(context.Background(), req, func(e Event) error {
+		received = append(received, e.Data)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []any{Greeting("hello, world")}, received)
+}
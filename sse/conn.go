@@ -0,0 +1,365 @@
+package sse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+)
+
+var (
+	// ErrClosed is returned when sending a message after the connection has
+	// been closed, e.g. because the client disconnected.
+	ErrClosed = errors.New("sse: connection closed")
+
+	// ErrDropped is returned when a message is dropped because the send buffer
+	// is full and the overflow policy is `OverflowDrop`.
+	ErrDropped = errors.New("sse: message dropped")
+
+	// ErrSlowClient is returned when the client is disconnected because the
+	// send buffer is full and the overflow policy is `OverflowDisconnect`.
+	ErrSlowClient = errors.New("sse: slow client disconnected")
+
+	// ErrUnknownEvent is reported via `Options.OnError` when sending data whose
+	// type is not in the event type map. The message is still sent using the
+	// default `message` event.
+	ErrUnknownEvent = errors.New("sse: unknown event type")
+)
+
+// OverflowPolicy determines what happens when a buffered sender's buffer is
+// full because the client is not reading messages fast enough.
+type OverflowPolicy int
+
+const (
+	// OverflowDrop drops new messages until there is room in the buffer.
+	OverflowDrop OverflowPolicy = iota
+
+	// OverflowDisconnect closes the connection to the client.
+	OverflowDisconnect
+)
+
+// conn is a single SSE connection to a client. Writes are serialized so that
+// messages and heartbeats may be sent from multiple goroutines.
+type conn struct {
+	api          huma.API
+	ctx          huma.Context
+	bw           io.Writer
+	typeToEvent  map[reflect.Type]string
+	contentTypes map[string]string
+	timeout      time.Duration
+	onError      func(ctx context.Context, err error)
+	log          *slog.Logger
+
+	mu     sync.Mutex
+	warned bool
+
+	// closed is canceled when the connection can no longer be written to. It
+	// is passed to the handler so it can stop sending messages.
+	closed context.Context
+	cancel context.CancelCauseFunc
+}
+
+// writeRaw writes and flushes the given bytes to the client, closing the
+// connection on failure.
+func (c *conn) writeRaw(b []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed.Err() != nil {
+		return ErrClosed
+	}
+
+	if d, ok := c.bw.(interface{ SetWriteDeadline(time.Time) error }); ok {
+		d.SetWriteDeadline(time.Now().Add(c.timeout))
+	} else if !c.warned {
+		// None of the built-in adapters support this, so it is not a warning.
+		c.log.Debug("unable to set write deadline")
+		c.warned = true
+	}
+
+	if _, err := c.bw.Write(b); err != nil {
+		c.cancel(err)
+		return err
+	}
+	if f, ok := c.bw.(http.Flusher); ok {
+		f.Flush()
+	} else {
+		err := fmt.Errorf("unable to flush: %w", http.ErrNotSupported)
+		c.report(err)
+		return err
+	}
+	return nil
+}
+
+// report an error via the `OnError` hook, falling back to logging it.
+func (c *conn) report(err error) {
+	if c.onError != nil {
+		c.onError(c.closed, err)
+		return
+	}
+	c.log.Error("sse error", "error", err)
+}
+
+// encode the data for an event using its configured content type. Events
+// without a content type are encoded as JSON.
+func (c *conn) encode(buf *bytes.Buffer, event string, data any) error {
+	ct := mediaType(c.contentTypes[event])
+	switch ct {
+	case "":
+		return json.NewEncoder(buf).Encode(data)
+	case "text/plain":
+		switch v := data.(type) {
+		case string:
+			buf.WriteString(v)
+		case []byte:
+			buf.Write(v)
+		default:
+			fmt.Fprint(buf, v)
+		}
+		return nil
+	default:
+		return c.api.Marshal(huma.WithBodyWriter(c.ctx, buf), "", ct, data)
+	}
+}
+
+// write sends a single message to the client.
+func (c *conn) write(msg Message) error {
+	buf := bytes.Buffer{}
+
+	// Write optional fields
+	if msg.ID > 0 {
+		buf.WriteString("id: " + strconv.Itoa(msg.ID) + "\n")
+	}
+	if msg.Retry > 0 {
+		buf.WriteString("retry: " + strconv.Itoa(msg.Retry) + "\n")
+	}
+
+	var event string
+	if msg.Data != nil {
+		var ok bool
+		event, ok = c.typeToEvent[deref(reflect.TypeOf(msg.Data))]
+		if !ok {
+			c.report(fmt.Errorf("%w: %s", ErrUnknownEvent, reflect.TypeOf(msg.Data)))
+		}
+	}
+	if event != "" && event != "message" {
+		// `message` is the default, so no need to transmit it.
+		buf.WriteString("event: " + event + "\n")
+	}
+
+	// Write the message data, one `data:` line per line of encoded data.
+	data := bytes.Buffer{}
+	encErr := c.encode(&data, event, msg.Data)
+	if encErr != nil {
+		encErr = fmt.Errorf("encode error: %w", encErr)
+		c.report(encErr)
+		data.Reset()
+		data.WriteString(`{"error": "` + encErr.Error() + `"}`)
+	}
+	lines := strings.ReplaceAll(strings.TrimSuffix(data.String(), "\n"), "\r\n", "\n")
+	for _, line := range strings.Split(strings.ReplaceAll(lines, "\r", "\n"), "\n") {
+		buf.WriteString("data: " + line + "\n")
+	}
+	buf.WriteString("\n")
+
+	if err := c.writeRaw(buf.Bytes()); err != nil {
+		return err
+	}
+	return encErr
+}
+
+// buffered returns a sender which queues messages to be written by `drain`,
+// applying the overflow policy when the queue is full.
+func (c *conn) buffered(queue chan Message, policy OverflowPolicy) Sender {
+	return func(msg Message) error {
+		if c.closed.Err() != nil {
+			return ErrClosed
+		}
+		select {
+		case queue <- msg:
+			return nil
+		default:
+		}
+		if policy == OverflowDisconnect {
+			c.cancel(ErrSlowClient)
+			return ErrSlowClient
+		}
+		return ErrDropped
+	}
+}
+
+// waiting returns a sender which queues messages to be written by `drain`,
+// waiting for room in the queue rather than applying the overflow policy.
+// It is used to replay missed messages, which may exceed the queue size.
+func (c *conn) waiting(queue chan Message) Sender {
+	return func(msg Message) error {
+		select {
+		case queue <- msg:
+			return nil
+		case <-c.closed.Done():
+			return ErrClosed
+		}
+	}
+}
+
+// waitingSenderKey is the context key for the sender a `Hub` uses to replay
+// missed messages to a buffered connection.
+type waitingSenderKey struct{}
+
+// drain writes queued messages until the connection is closed. Once `done`
+// is closed any remaining queued messages are written before returning.
+func (c *conn) drain(queue chan Message, done <-chan struct{}) {
+	for {
+		select {
+		case msg := <-queue:
+			c.write(msg)
+		case <-c.closed.Done():
+			return
+		case <-done:
+			for {
+				select {
+				case msg := <-queue:
+					c.write(msg)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// heartbeat periodically sends a comment to keep the connection alive
+// through proxies and load balancers which close idle connections.
+func (c *conn) heartbeat(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.writeRaw([]byte(": keepalive\n\n"))
+		case <-c.closed.Done():
+			return
+		case <-done:
+			return
+		}
+	}
+}
+
+// serve streams messages from the handler to the client until the handler
+// returns, then calls the `OnClose` hook if set.
+func serve(api huma.API, ctx huma.Context, opts Options, typeToEvent map[reflect.Type]string, handler func(ctx context.Context, send Sender)) {
+	ctx.SetHeader("Content-Type", "text/event-stream")
+
+	c := &conn{
+		api:          api,
+		ctx:          ctx,
+		bw:           ctx.BodyWriter(),
+		typeToEvent:  typeToEvent,
+		contentTypes: opts.ContentTypes,
+		timeout:      opts.WriteTimeout,
+		onError:      opts.OnError,
+		log:          huma.RequestLogger(api, ctx),
+	}
+	if c.timeout <= 0 {
+		c.timeout = WriteTimeout
+	}
+	c.closed, c.cancel = context.WithCancelCause(ctx.Context())
+	defer c.cancel(nil)
+
+	// Make the client's last event ID available to the handler, e.g. so a
+	// `Hub` can replay missed messages.
+	handlerCtx := context.Context(c.closed)
+	lastID, lastErr := strconv.Atoi(ctx.Header("Last-Event-ID"))
+	if lastErr == nil {
+		handlerCtx = context.WithValue(handlerCtx, lastEventIDKey{}, lastID)
+	}
+
+	var stream string
+	if opts.Store != nil {
+		stream = opts.StreamID(ctx)
+
+		// Replay any messages the client missed before continuing.
+		if lastErr == nil {
+			missed, err := opts.Store.Since(stream, lastID)
+			if err != nil {
+				c.report(fmt.Errorf("unable to load missed messages: %w", err))
+			}
+			for _, msg := range missed {
+				if err := c.write(msg); err != nil && c.closed.Err() != nil {
+					break
+				}
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	send := Sender(c.write)
+	if opts.BufferSize > 0 {
+		queue := make(chan Message, opts.BufferSize)
+		send = c.buffered(queue, opts.Overflow)
+		handlerCtx = context.WithValue(handlerCtx, waitingSenderKey{}, c.waiting(queue))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.drain(queue, done)
+		}()
+	}
+
+	if opts.Store != nil {
+		next := send
+		send = func(msg Message) error {
+			if c.closed.Err() != nil {
+				return ErrClosed
+			}
+			msg, err := opts.Store.Append(stream, msg)
+			if err != nil {
+				return err
+			}
+			return next(msg)
+		}
+	}
+
+	if opts.Heartbeat > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.heartbeat(opts.Heartbeat, done)
+		}()
+	}
+
+	// Call the user-provided SSE handler unless the client already went away.
+	if c.closed.Err() == nil {
+		handler(handlerCtx, send)
+	}
+
+	close(done)
+	wg.Wait()
+
+	// The cause is `nil` if the connection is still open, i.e. the handler
+	// finished normally.
+	err := context.Cause(c.closed)
+	c.cancel(nil)
+	switch {
+	case err == nil:
+	case errors.Is(err, context.Canceled):
+		c.log.Debug("sse client disconnected")
+	default:
+		c.log.Warn("sse connection closed", "error", err)
+	}
+	if opts.OnClose != nil {
+		opts.OnClose(ctx.Context(), err)
+	}
+}
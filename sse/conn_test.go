@@ -0,0 +1,184 @@
+package sse
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// testWriter is a response writer which can fail or block writes.
+type testWriter struct {
+	mu      sync.Mutex
+	header  http.Header
+	buf     bytes.Buffer
+	err     error
+	onWrite func()
+}
+
+func (w *testWriter) Header() http.Header {
+	if w.header == nil {
+		w.header = http.Header{}
+	}
+	return w.header
+}
+
+func (w *testWriter) Write(b []byte) (int, error) {
+	if w.onWrite != nil {
+		w.onWrite()
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return 0, w.err
+	}
+	return w.buf.Write(b)
+}
+
+func (w *testWriter) WriteHeader(int) {}
+
+func (w *testWriter) Flush() {}
+
+func (w *testWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+var testEvents = map[reflect.Type]string{
+	reflect.TypeOf(DefaultMessage{}): "message",
+}
+
+func testServe(r *http.Request, w http.ResponseWriter, opts Options, handler func(ctx context.Context, send Sender)) {
+	api := huma.NewAPI(huma.DefaultConfig("Test API", "1.0.0"), humatest.NewAdapter(chi.NewRouter()))
+	serve(api, humatest.NewContext(nil, r, w), opts, testEvents, handler)
+}
+
+func TestWriteDeadlineLog(t *testing.T) {
+	logs := &bytes.Buffer{}
+	config := huma.DefaultConfig("Test API", "1.0.0")
+	config.Logger = slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	api := huma.NewAPI(config, humatest.NewAdapter(chi.NewRouter()))
+
+	// The writer does not support deadlines, which is too common to warn
+	// about on every connection.
+	w := &testWriter{}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	serve(api, humatest.NewContext(nil, r, w), Options{}, testEvents, func(ctx context.Context, send Sender) {
+		send.Data(DefaultMessage{Message: "hello"})
+	})
+
+	assert.Contains(t, logs.String(), "level=DEBUG msg=\"unable to set write deadline\"")
+	assert.NotContains(t, logs.String(), "level=WARN")
+}
+
+func TestHeartbeat(t *testing.T) {
+	w := &testWriter{}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	testServe(r, w, Options{Heartbeat: 5 * time.Millisecond}, func(ctx context.Context, send Sender) {
+		time.Sleep(30 * time.Millisecond)
+		send.Data(DefaultMessage{Message: "done"})
+	})
+
+	assert.Contains(t, w.String(), ": keepalive\n\n")
+	assert.Contains(t, w.String(), "data: {\"message\":\"done\"}\n\n")
+}
+
+func TestOnClose(t *testing.T) {
+	w := &testWriter{}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	var closed []error
+	opts := Options{
+		OnClose: func(ctx context.Context, err error) {
+			closed = append(closed, err)
+		},
+	}
+
+	// Normal completion.
+	testServe(r, w, opts, func(ctx context.Context, send Sender) {
+		assert.NoError(t, send.Data(DefaultMessage{Message: "hello"}))
+	})
+	assert.Equal(t, []error{nil}, closed)
+
+	// Write failure closes the connection.
+	closed = nil
+	w.err = errors.New("broken pipe")
+	testServe(r, w, opts, func(ctx context.Context, send Sender) {
+		assert.Error(t, send.Data(DefaultMessage{Message: "hello"}))
+		assert.Error(t, ctx.Err())
+		assert.ErrorIs(t, send.Data(DefaultMessage{Message: "hello"}), ErrClosed)
+	})
+	assert.Len(t, closed, 1)
+	assert.EqualError(t, closed[0], "broken pipe")
+
+	// Client disconnect cancels the handler's context.
+	closed = nil
+	w.err = nil
+	reqCtx, cancel := context.WithCancel(context.Background())
+	testServe(r.WithContext(reqCtx), w, opts, func(ctx context.Context, send Sender) {
+		cancel()
+		<-ctx.Done()
+		assert.ErrorIs(t, send.Data(DefaultMessage{Message: "hello"}), ErrClosed)
+	})
+	assert.Equal(t, []error{context.Canceled}, closed)
+}
+
+func TestBufferedOverflow(t *testing.T) {
+	for _, policy := range []OverflowPolicy{OverflowDrop, OverflowDisconnect} {
+		started := make(chan struct{}, 1)
+		release := make(chan struct{})
+		w := &testWriter{onWrite: func() {
+			select {
+			case started <- struct{}{}:
+				// Block the first write to simulate a slow client.
+				<-release
+			default:
+			}
+		}}
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+		var closeErr error
+		testServe(r, w, Options{
+			BufferSize: 1,
+			Overflow:   policy,
+			OnClose: func(ctx context.Context, err error) {
+				closeErr = err
+			},
+		}, func(ctx context.Context, send Sender) {
+			assert.NoError(t, send.Data(DefaultMessage{Message: "one"}))
+			<-started
+			assert.NoError(t, send.Data(DefaultMessage{Message: "two"}))
+
+			err := send.Data(DefaultMessage{Message: "three"})
+			if policy == OverflowDrop {
+				assert.ErrorIs(t, err, ErrDropped)
+				assert.NoError(t, ctx.Err())
+			} else {
+				assert.ErrorIs(t, err, ErrSlowClient)
+				assert.Error(t, ctx.Err())
+			}
+			close(release)
+		})
+
+		if policy == OverflowDrop {
+			assert.NoError(t, closeErr)
+			assert.Equal(t, "data: {\"message\":\"one\"}\n\ndata: {\"message\":\"two\"}\n\n", w.String())
+		} else {
+			assert.ErrorIs(t, closeErr, ErrSlowClient)
+			assert.NotContains(t, w.String(), "three")
+		}
+	}
+}
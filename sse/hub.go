@@ -0,0 +1,306 @@
+package sse
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// subscriber is a single sender attached to a hub topic.
+type subscriber[T any] struct {
+	send   Sender
+	filter func(T) bool
+
+	// While missed messages are replayed, published messages are queued to
+	// be sent afterward so the subscriber receives them in order.
+	mu        sync.Mutex
+	replaying bool
+	queued    []Message
+}
+
+// accepts returns whether the message passes the subscriber's filter.
+func (s *subscriber[T]) accepts(msg Message) bool {
+	data, ok := msg.Data.(T)
+	return !ok || s.filter == nil || s.filter(data)
+}
+
+// topicLock serializes publishing to a single topic.
+type topicLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// HubStats describes the current state of a hub and the messages which have
+// passed through it.
+type HubStats struct {
+	// Topics is the number of topics with at least one subscriber.
+	Topics int
+
+	// Subscribers is the total number of active subscribers.
+	Subscribers int
+
+	// Published is the number of messages published to the hub.
+	Published uint64
+
+	// Delivered is the number of messages sent to subscribers.
+	Delivered uint64
+
+	// Failed is the number of messages which could not be sent to a
+	// subscriber, e.g. because it was dropped or the client went away.
+	Failed uint64
+}
+
+// HubOptions configures optional hub behavior.
+type HubOptions struct {
+	// Store, if set, records each published message once per topic and
+	// assigns its ID. Subscribers whose handler context has a `Last-Event-ID`
+	// (see `LastEventID`) are first sent the retained messages they missed
+	// which pass their filter. Do not also set `Options.Store` on the SSE
+	// operation, as that would record each message once per subscriber.
+	Store Store
+}
+
+// Hub broadcasts messages of type `T` to any number of SSE subscribers
+// grouped by topic. It is safe for concurrent use.
+//
+//	hub := sse.NewHub[StatusEvent]()
+//
+//	sse.Register(api, op, eventTypeMap, func(ctx context.Context, input *StatusInput, send sse.Sender) {
+//		hub.Attach(ctx, "status", send, func(e StatusEvent) bool {
+//			return e.Region == input.Region
+//		})
+//	}, sse.Options{BufferSize: 16})
+//
+//	// Elsewhere...
+//	hub.Publish("status", StatusEvent{Region: "us-west", Status: "ok"})
+//
+// Publishing calls each subscriber's sender in turn, and publishes to the
+// same topic are sent one at a time so every subscriber receives them in ID
+// order. Senders should be buffered via `Options.BufferSize` to prevent a
+// slow client from delaying delivery to everyone else.
+type Hub[T any] struct {
+	mu     sync.RWMutex
+	topics map[string]map[*subscriber[T]]struct{}
+	store  Store
+
+	locksMu sync.Mutex
+	locks   map[string]*topicLock
+
+	published atomic.Uint64
+	delivered atomic.Uint64
+	failed    atomic.Uint64
+}
+
+// NewHub creates a new empty hub. Optional `HubOptions` may be passed to
+// enable resuming streams via `Last-Event-ID`.
+func NewHub[T any](options ...HubOptions) *Hub[T] {
+	var opts HubOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	return &Hub[T]{
+		topics: map[string]map[*subscriber[T]]struct{}{},
+		store:  opts.Store,
+		locks:  map[string]*topicLock{},
+	}
+}
+
+// lockTopic locks the topic for publishing, returning the unlock function.
+func (h *Hub[T]) lockTopic(topic string) func() {
+	h.locksMu.Lock()
+	l := h.locks[topic]
+	if l == nil {
+		l = &topicLock{}
+		h.locks[topic] = l
+	}
+	l.refs++
+	h.locksMu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		h.locksMu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(h.locks, topic)
+		}
+		h.locksMu.Unlock()
+	}
+}
+
+// Subscribe attaches the sender to the topic until the context is done or
+// the returned unsubscribe function is called. If `filter` is not `nil` then
+// only messages for which it returns `true` are sent. If the hub has a store
+// and the context has a `Last-Event-ID` then missed messages are sent first,
+// waiting for room in the send buffer rather than applying the overflow
+// policy so that a long replay is not truncated.
+func (h *Hub[T]) Subscribe(ctx context.Context, topic string, send Sender, filter func(T) bool) (unsubscribe func()) {
+	sub := &subscriber[T]{send: send, filter: filter}
+	lastID, resume := LastEventID(ctx)
+	resume = resume && h.store != nil
+	sub.replaying = resume
+
+	h.mu.Lock()
+	if h.topics[topic] == nil {
+		h.topics[topic] = map[*subscriber[T]]struct{}{}
+	}
+	h.topics[topic][sub] = struct{}{}
+	h.mu.Unlock()
+
+	if resume {
+		h.replay(ctx, topic, sub, lastID)
+	}
+
+	stop := make(chan struct{})
+	var once sync.Once
+	unsubscribe = func() {
+		once.Do(func() {
+			close(stop)
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			delete(h.topics[topic], sub)
+			if len(h.topics[topic]) == 0 {
+				delete(h.topics, topic)
+			}
+		})
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			unsubscribe()
+		case <-stop:
+		}
+	}()
+
+	return unsubscribe
+}
+
+// Attach subscribes the sender to the topic and blocks until the context is
+// done, e.g. because the client disconnected. This is convenient to call at
+// the end of an `sse.Register` handler.
+func (h *Hub[T]) Attach(ctx context.Context, topic string, send Sender, filter func(T) bool) {
+	unsubscribe := h.Subscribe(ctx, topic, send, filter)
+	defer unsubscribe()
+	<-ctx.Done()
+}
+
+// Publish sends the data to all subscribers of the topic whose filter
+// accepts it, returning the number of subscribers it was sent to.
+//
+// If the hub has a store the message is recorded once for the topic before
+// it is sent. Should recording fail the message is still sent, but without
+// an ID, so clients cannot resume from it.
+func (h *Hub[T]) Publish(topic string, data T) int {
+	h.published.Add(1)
+
+	msg := Message{Data: data}
+
+	// Recording and sending happen under the topic lock so subscribers
+	// receive messages in the order of their IDs.
+	unlock := h.lockTopic(topic)
+	defer unlock()
+
+	if h.store != nil {
+		if stored, err := h.store.Append(topic, msg); err == nil {
+			msg = stored
+		}
+	}
+
+	h.mu.RLock()
+	subs := make([]*subscriber[T], 0, len(h.topics[topic]))
+	for sub := range h.topics[topic] {
+		subs = append(subs, sub)
+	}
+	h.mu.RUnlock()
+
+	sent := 0
+	for _, sub := range subs {
+		if !sub.accepts(msg) {
+			continue
+		}
+		sub.mu.Lock()
+		if sub.replaying {
+			sub.queued = append(sub.queued, msg)
+			sub.mu.Unlock()
+			sent++
+			continue
+		}
+		sub.mu.Unlock()
+		if h.deliver(sub.send, msg) {
+			sent++
+		}
+	}
+	return sent
+}
+
+// replay sends the messages the subscriber missed since `lastID`, followed
+// by any published while replaying. It runs without holding the hub's locks
+// so a slow subscriber does not delay publishing to others.
+func (h *Hub[T]) replay(ctx context.Context, topic string, sub *subscriber[T], lastID int) {
+	send := sub.send
+	if wait, ok := ctx.Value(waitingSenderKey{}).(Sender); ok {
+		send = wait
+	}
+
+	if missed, err := h.store.Since(topic, lastID); err == nil {
+		for _, msg := range missed {
+			if sub.accepts(msg) {
+				h.deliver(send, msg)
+			}
+			lastID = msg.ID
+		}
+	}
+
+	for {
+		sub.mu.Lock()
+		queued := sub.queued
+		sub.queued = nil
+		sub.replaying = len(queued) > 0
+		sub.mu.Unlock()
+		if len(queued) == 0 {
+			return
+		}
+		for _, msg := range queued {
+			if msg.ID > 0 && msg.ID <= lastID {
+				// Already sent as part of the replay.
+				continue
+			}
+			h.deliver(send, msg)
+		}
+	}
+}
+
+// deliver sends the message, returning whether it was sent.
+func (h *Hub[T]) deliver(send Sender, msg Message) bool {
+	if err := send(msg); err != nil {
+		h.failed.Add(1)
+		return false
+	}
+	h.delivered.Add(1)
+	return true
+}
+
+// Subscribers returns the number of active subscribers for the topic.
+func (h *Hub[T]) Subscribers(topic string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.topics[topic])
+}
+
+// Stats returns the current hub statistics, e.g. for exporting as metrics.
+func (h *Hub[T]) Stats() HubStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stats := HubStats{
+		Topics:    len(h.topics),
+		Published: h.published.Load(),
+		Delivered: h.delivered.Load(),
+		Failed:    h.failed.Load(),
+	}
+	for _, subs := range h.topics {
+		stats.Subscribers += len(subs)
+	}
+	return stats
+}
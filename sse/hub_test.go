@@ -0,0 +1,247 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHub(t *testing.T) {
+	hub := NewHub[DefaultMessage]()
+
+	var mu sync.Mutex
+	received := map[string][]string{}
+	sender := func(name string) Sender {
+		return func(msg Message) error {
+			mu.Lock()
+			defer mu.Unlock()
+			received[name] = append(received[name], msg.Data.(DefaultMessage).Message)
+			return nil
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	unsubA := hub.Subscribe(ctx, "news", sender("a"), nil)
+	hub.Subscribe(ctx, "news", sender("b"), func(m DefaultMessage) bool {
+		return m.Message != "skip"
+	})
+	hub.Subscribe(ctx, "other", sender("c"), nil)
+	hub.Subscribe(ctx, "other", func(msg Message) error {
+		return errors.New("failed")
+	}, nil)
+
+	assert.Equal(t, 2, hub.Subscribers("news"))
+	assert.Equal(t, HubStats{Topics: 2, Subscribers: 4}, hub.Stats())
+
+	assert.Equal(t, 2, hub.Publish("news", DefaultMessage{Message: "one"}))
+	assert.Equal(t, 1, hub.Publish("news", DefaultMessage{Message: "skip"}))
+	assert.Equal(t, 1, hub.Publish("other", DefaultMessage{Message: "two"}))
+	assert.Equal(t, 0, hub.Publish("missing", DefaultMessage{Message: "three"}))
+
+	assert.Equal(t, map[string][]string{
+		"a": {"one", "skip"},
+		"b": {"one"},
+		"c": {"two"},
+	}, received)
+
+	unsubA()
+	unsubA()
+	assert.Equal(t, 1, hub.Subscribers("news"))
+
+	// Canceling the context unsubscribes everyone.
+	cancel()
+	assert.Eventually(t, func() bool {
+		return hub.Stats().Subscribers == 0
+	}, time.Second, time.Millisecond)
+
+	assert.Equal(t, HubStats{
+		Published: 4,
+		Delivered: 4,
+		Failed:    1,
+	}, hub.Stats())
+}
+
+func TestHubAttach(t *testing.T) {
+	hub := NewHub[DefaultMessage]()
+	w := &testWriter{}
+	reqCtx, cancel := context.WithCancel(context.Background())
+	r := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(reqCtx)
+
+	go func() {
+		assert.Eventually(t, func() bool {
+			return hub.Subscribers("news") == 1
+		}, time.Second, time.Millisecond)
+		hub.Publish("news", DefaultMessage{Message: "hello"})
+		// Simulate the client disconnecting.
+		cancel()
+	}()
+
+	testServe(r, w, Options{}, func(ctx context.Context, send Sender) {
+		hub.Attach(ctx, "news", send, nil)
+	})
+
+	assert.Equal(t, 0, hub.Subscribers("news"))
+	assert.Contains(t, w.String(), "data: {\"message\":\"hello\"}\n\n")
+}
+
+func TestHubStore(t *testing.T) {
+	store := NewMemoryStore(10)
+	hub := NewHub[DefaultMessage](HubOptions{Store: store})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ids := map[string][]int{}
+	sender := func(name string) Sender {
+		return func(msg Message) error {
+			ids[name] = append(ids[name], msg.ID)
+			return nil
+		}
+	}
+	hub.Subscribe(ctx, "news", sender("a"), nil)
+	hub.Subscribe(ctx, "news", sender("b"), nil)
+
+	hub.Publish("news", DefaultMessage{Message: "one"})
+	hub.Publish("news", DefaultMessage{Message: "skip"})
+	hub.Publish("news", DefaultMessage{Message: "two"})
+
+	// Each message is recorded once regardless of the number of subscribers.
+	assert.Equal(t, []int{1, 2, 3}, ids["a"])
+	assert.Equal(t, []int{1, 2, 3}, ids["b"])
+	missed, err := store.Since("news", 0)
+	assert.NoError(t, err)
+	assert.Len(t, missed, 3)
+
+	// Reconnecting replays only missed messages which pass the filter.
+	var replayed []string
+	resumed := context.WithValue(ctx, lastEventIDKey{}, 1)
+	hub.Subscribe(resumed, "news", func(msg Message) error {
+		replayed = append(replayed, msg.Data.(DefaultMessage).Message)
+		return nil
+	}, func(m DefaultMessage) bool {
+		return m.Message != "skip"
+	})
+	assert.Equal(t, []string{"two"}, replayed)
+
+	hub.Publish("news", DefaultMessage{Message: "three"})
+	assert.Equal(t, []string{"two", "three"}, replayed)
+}
+
+func TestHubLastEventID(t *testing.T) {
+	hub := NewHub[DefaultMessage](HubOptions{Store: NewMemoryStore(10)})
+	hub.Publish("news", DefaultMessage{Message: "one"})
+	hub.Publish("news", DefaultMessage{Message: "two"})
+
+	w := &testWriter{}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Last-Event-ID", "1")
+
+	testServe(r, w, Options{}, func(ctx context.Context, send Sender) {
+		id, ok := LastEventID(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, 1, id)
+		unsubscribe := hub.Subscribe(ctx, "news", send, nil)
+		unsubscribe()
+	})
+
+	assert.Equal(t, "id: 2\ndata: {\"message\":\"two\"}\n\n", w.String())
+}
+
+func TestHubReplayBuffered(t *testing.T) {
+	hub := NewHub[DefaultMessage](HubOptions{Store: NewMemoryStore(10)})
+	for i := 0; i < 5; i++ {
+		hub.Publish("news", DefaultMessage{Message: "hello"})
+	}
+
+	w := &testWriter{}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Last-Event-ID", "0")
+
+	// The replay is longer than the buffer but is not dropped.
+	testServe(r, w, Options{BufferSize: 1, Overflow: OverflowDrop}, func(ctx context.Context, send Sender) {
+		unsubscribe := hub.Subscribe(ctx, "news", send, nil)
+		unsubscribe()
+	})
+
+	for _, id := range []string{"1", "2", "3", "4", "5"} {
+		assert.Contains(t, w.String(), "id: "+id+"\n")
+	}
+}
+
+func TestHubReplayConcurrent(t *testing.T) {
+	hub := NewHub[DefaultMessage](HubOptions{Store: NewMemoryStore(10)})
+	hub.Publish("news", DefaultMessage{Message: "one"})
+	hub.Publish("news", DefaultMessage{Message: "two"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var replayed []string
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	send := func(msg Message) error {
+		once.Do(func() {
+			close(started)
+			<-release
+		})
+		mu.Lock()
+		defer mu.Unlock()
+		replayed = append(replayed, msg.Data.(DefaultMessage).Message)
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		hub.Subscribe(context.WithValue(ctx, lastEventIDKey{}, 0), "news", send, nil)
+	}()
+
+	// A slow replay does not block publishing, and messages published in the
+	// meantime are sent afterward in order.
+	<-started
+	assert.Equal(t, 1, hub.Publish("news", DefaultMessage{Message: "three"}))
+	close(release)
+	<-done
+
+	hub.Publish("news", DefaultMessage{Message: "four"})
+	assert.Equal(t, []string{"one", "two", "three", "four"}, replayed)
+}
+
+func TestHubPublishOrder(t *testing.T) {
+	hub := NewHub[DefaultMessage](HubOptions{Store: NewMemoryStore(100)})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var ids []int
+	hub.Subscribe(ctx, "news", func(msg Message) error {
+		ids = append(ids, msg.ID)
+		return nil
+	}, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hub.Publish("news", DefaultMessage{Message: "hello"})
+		}()
+	}
+	wg.Wait()
+
+	// Messages are delivered in the order of their IDs.
+	assert.Len(t, ids, 50)
+	for i, id := range ids {
+		assert.Equal(t, i+1, id)
+	}
+}
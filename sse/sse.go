@@ -3,11 +3,8 @@ package sse
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
-	"net/http"
 	"reflect"
-	"runtime/debug"
+	"strings"
 	"time"
 
 	"github.com/danielgtaylor/huma/v2"
@@ -16,6 +13,16 @@ import (
 // WriteTimeout is the timeout for writing to the client.
 var WriteTimeout = 5 * time.Second
 
+// MetadataKey is the operation metadata key under which `Register` stores a
+// `map[string]*huma.Schema` of event names to their data schemas, e.g. for
+// generating AsyncAPI documents.
+const MetadataKey = "sse"
+
+// ContentTypesMetadataKey is the operation metadata key under which
+// `Register` stores a `map[string]string` of event names to the content type
+// of their data from `Options.ContentTypes`. Events not in the map are JSON.
+const ContentTypesMetadataKey = "sse:contentTypes"
+
 // deref follows pointers until it finds a non-pointer type.
 func deref(t reflect.Type) reflect.Type {
 	for t.Kind() == reflect.Ptr {
@@ -24,6 +31,37 @@ func deref(t reflect.Type) reflect.Type {
 	return t
 }
 
+// lastEventIDKey is the context key for the client's `Last-Event-ID`.
+type lastEventIDKey struct{}
+
+// LastEventID returns the ID sent by a reconnecting client via the
+// `Last-Event-ID` header, if any, from the context passed to an SSE handler.
+func LastEventID(ctx context.Context) (int, bool) {
+	id, ok := ctx.Value(lastEventIDKey{}).(int)
+	return id, ok
+}
+
+// mediaType returns the content type without any parameters.
+func mediaType(ct string) string {
+	if i := strings.IndexByte(ct, ';'); i != -1 {
+		ct = ct[:i]
+	}
+	return strings.TrimSpace(ct)
+}
+
+// isJSON returns true if event data in the content type is JSON.
+func isJSON(ct string) bool {
+	mt := mediaType(ct)
+	return mt == "" || mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
+
+// isText returns true if event data in the content type is text, which is
+// required to send it in `data:` lines. Binary formats like CBOR are not.
+func isText(ct string) bool {
+	mt := mediaType(ct)
+	return isJSON(mt) || strings.HasPrefix(mt, "text/") || strings.HasSuffix(mt, "yaml") || strings.HasSuffix(mt, "xml")
+}
+
 // Message is a single SSE message. There is no `event` field as this is
 // handled by the `eventTypeMap` when registering the operation.
 type Message struct {
@@ -43,12 +81,80 @@ func (s Sender) Data(data any) error {
 	return s(Message{Data: data})
 }
 
+// Options configures optional SSE behavior when registering an operation.
+type Options struct {
+	// Store, if set, records sent messages and assigns IDs to messages without
+	// one. Clients reconnecting with a `Last-Event-ID` header are first sent
+	// any retained messages they missed before live messages continue.
+	// Requires `StreamID` to be set. Leave this unset when sending messages
+	// from a `Hub` created with a store, since the hub records each message
+	// once and replays it itself.
+	Store Store
+
+	// StreamID returns the key used to group messages in the `Store` for a
+	// request and is required when `Store` is set. Every message in a stream
+	// is replayed to any client whose request maps to it, so a stream must
+	// only hold messages meant for all of its subscribers. Include e.g. the
+	// authenticated user in the key for per-user streams.
+	StreamID func(ctx huma.Context) string
+
+	// Heartbeat, if set, sends a comment at this interval to keep idle
+	// connections open through proxies and load balancers.
+	Heartbeat time.Duration
+
+	// WriteTimeout is the deadline for writing each message to the client.
+	// Defaults to the package-level `WriteTimeout`.
+	WriteTimeout time.Duration
+
+	// BufferSize, if set, queues up to this many messages which are written to
+	// the client in the background so that a slow client does not block the
+	// handler. When the buffer is full the `Overflow` policy is applied.
+	BufferSize int
+
+	// Overflow determines what happens when the send buffer is full.
+	Overflow OverflowPolicy
+
+	// ContentTypes maps event names to the content type used to encode their
+	// data. `text/plain` sends strings, byte slices, and other values as
+	// text, while other content types use the API's configured `Formats`.
+	// Events not in this map are encoded as JSON. Only text formats can be
+	// sent, so binary formats like CBOR panic at registration.
+	ContentTypes map[string]string
+
+	// OnError, if set, is called when a message cannot be encoded or flushed,
+	// or when its data type is not in the event type map. Otherwise these
+	// errors are logged via the API's `Logger`.
+	OnError func(ctx context.Context, err error)
+
+	// OnClose, if set, is called once the stream has ended. The error is `nil`
+	// if the handler returned normally, otherwise it describes why the
+	// connection was closed, e.g. `context.Canceled` if the client went away
+	// or `ErrSlowClient`.
+	OnClose func(ctx context.Context, err error)
+}
+
 // Register a new SSE operation. The `eventTypeMap` maps from event name to
 // the type of the data that will be sent. The `f` function is called with
 // the context, input, and a `send` function that can be used to send messages
 // to the client. Flushing is handled automatically as long as the adapter's
-// `BodyWriter` implements `http.Flusher`.
-func Register[I any](api huma.API, op huma.Operation, eventTypeMap map[string]any, f func(ctx context.Context, input *I, send Sender)) {
+// `BodyWriter` implements `http.Flusher`. The context passed to `f` is
+// canceled when the client disconnects or the connection fails. Optional
+// `Options` may be passed to enable features like resuming streams via
+// `Last-Event-ID`, heartbeats, and buffering.
+func Register[I any](api huma.API, op huma.Operation, eventTypeMap map[string]any, f func(ctx context.Context, input *I, send Sender), options ...Options) {
+	var opts Options
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.Store != nil && opts.StreamID == nil {
+		panic("sse: Options.StreamID is required when Options.Store is set")
+	}
+	for event, ct := range opts.ContentTypes {
+		if !isText(ct) {
+			panic("sse: content type " + ct + " for event " + event + " is not a text format")
+		}
+	}
+
 	// Start by defining the SSE schema & operation response.
 	if op.Responses == nil {
 		op.Responses = map[string]*huma.Response{}
@@ -61,10 +167,23 @@ func Register[I any](api huma.API, op huma.Operation, eventTypeMap map[string]an
 	}
 
 	typeToEvent := make(map[reflect.Type]string, len(eventTypeMap))
-	dataSchemas := make([]*huma.Schema, len(eventTypeMap))
+	dataSchemas := make([]*huma.Schema, 0, len(eventTypeMap))
+	events := make(map[string]*huma.Schema, len(eventTypeMap))
 	for k, v := range eventTypeMap {
 		vt := deref(reflect.TypeOf(v))
 		typeToEvent[vt] = k
+		events[k] = api.OpenAPI().Components.Schemas.Schema(vt, true, k)
+		data := events[k]
+		if ct := opts.ContentTypes[k]; !isJSON(ct) {
+			// The data is sent as text in the given format rather than JSON.
+			data = &huma.Schema{
+				Type: huma.TypeString,
+				Extensions: map[string]interface{}{
+					"contentMediaType": ct,
+					"contentSchema":    events[k],
+				},
+			}
+		}
 		required := []string{"data"}
 		if k != "" && k != "message" {
 			required = append(required, "event")
@@ -84,7 +203,7 @@ func Register[I any](api huma.API, op huma.Operation, eventTypeMap map[string]an
 						"const": k,
 					},
 				},
-				"data": api.OpenAPI().Components.Schemas.Schema(vt, true, k),
+				"data": data,
 				"retry": {
 					Type:        huma.TypeInteger,
 					Description: "The retry time in milliseconds.",
@@ -110,62 +229,38 @@ func Register[I any](api huma.API, op huma.Operation, eventTypeMap map[string]an
 		Schema: schema,
 	}
 
+	metadata := make(map[string]any, len(op.Metadata)+2)
+	for k, v := range op.Metadata {
+		metadata[k] = v
+	}
+	metadata[MetadataKey] = events
+	if len(opts.ContentTypes) > 0 {
+		contentTypes := make(map[string]string, len(opts.ContentTypes))
+		for k, v := range opts.ContentTypes {
+			contentTypes[k] = v
+		}
+		metadata[ContentTypesMetadataKey] = contentTypes
+	}
+	op.Metadata = metadata
+
+	if opts.Store != nil {
+		op.Parameters = append(op.Parameters, &huma.Param{
+			Name:        "Last-Event-ID",
+			In:          "header",
+			Description: "The ID of the last event received, used to resume the stream after reconnecting.",
+			Schema:      &huma.Schema{Type: huma.TypeInteger},
+		})
+	}
+
 	// Register the operation with the API, using the built-in streaming
 	// response callback functionality. This will call the user's `f` function
 	// and provide a `send` function to simplify sending messages.
 	huma.Register(api, op, func(ctx context.Context, input *I) (*huma.StreamResponse, error) {
 		return &huma.StreamResponse{
 			Body: func(ctx huma.Context) {
-				ctx.SetHeader("Content-Type", "text/event-stream")
-				bw := ctx.BodyWriter()
-				encoder := json.NewEncoder(bw)
-				send := func(msg Message) error {
-					if d, ok := bw.(interface{ SetWriteDeadline(time.Time) error }); ok {
-						d.SetWriteDeadline(time.Now().Add(WriteTimeout))
-					} else {
-						fmt.Println("warning: unable to set write deadline")
-					}
-
-					// Write optional fields
-					if msg.ID > 0 {
-						bw.Write([]byte(fmt.Sprintf("id: %d\n", msg.ID)))
-					}
-					if msg.Retry > 0 {
-						bw.Write([]byte(fmt.Sprintf("retry: %d\n", msg.Retry)))
-					}
-
-					event, ok := typeToEvent[deref(reflect.TypeOf(msg.Data))]
-					if !ok {
-						fmt.Println("error: unknown event type", reflect.TypeOf(msg.Data))
-						debug.PrintStack()
-					}
-					if event != "" && event != "message" {
-						// `message` is the default, so no need to transmit it.
-						bw.Write([]byte("event: " + event + "\n"))
-					}
-
-					// Write the message data.
-					if _, err := bw.Write([]byte("data: ")); err != nil {
-						return err
-					}
-					if err := encoder.Encode(msg.Data); err != nil {
-						bw.Write([]byte(`{"error": "encode error: `))
-						bw.Write([]byte(err.Error()))
-						bw.Write([]byte("\"}\n\n"))
-						return err
-					}
-					bw.Write([]byte("\n"))
-					if f, ok := bw.(http.Flusher); ok {
-						f.Flush()
-					} else {
-						fmt.Println("error: unable to flush")
-						return fmt.Errorf("unable to flush: %w", http.ErrNotSupported)
-					}
-					return nil
-				}
-
-				// Call the user-provided SSE handler.
-				f(ctx.Context(), input, send)
+				serve(api, ctx, opts, typeToEvent, func(ctx context.Context, send Sender) {
+					f(ctx, input, send)
+				})
 			},
 		}, nil
 	})
@@ -1,8 +1,15 @@
 package sse
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/danielgtaylor/huma/v2"
@@ -73,3 +80,240 @@ data: {"error": "encode error: json: unsupported type: chan int"}
 
 `, resp.Body.String())
 }
+
+func TestSSEResume(t *testing.T) {
+	_, api := humatest.New(t)
+
+	store := NewMemoryStore(10)
+	count := 0
+
+	Register(api, huma.Operation{
+		OperationID: "sse-resume",
+		Method:      http.MethodGet,
+		Path:        "/resume",
+	}, map[string]any{
+		"message": DefaultMessage{},
+	}, func(ctx context.Context, input *struct{}, send Sender) {
+		count++
+		send.Data(DefaultMessage{Message: "first " + strconv.Itoa(count)})
+		send.Data(DefaultMessage{Message: "second " + strconv.Itoa(count)})
+	}, Options{Store: store, StreamID: func(ctx huma.Context) string {
+		return "resume"
+	}})
+
+	// Streams may hold other users' messages, so the key must be explicit.
+	assert.Panics(t, func() {
+		Register(api, huma.Operation{
+			Method: http.MethodGet,
+			Path:   "/resume-default",
+		}, map[string]any{"message": DefaultMessage{}}, func(ctx context.Context, input *struct{}, send Sender) {}, Options{Store: store})
+	})
+
+	param := api.OpenAPI().Paths["/resume"].Get.Parameters[0]
+	assert.Equal(t, "Last-Event-ID", param.Name)
+	assert.Equal(t, "header", param.In)
+
+	resp := api.Get("/resume")
+	assert.Equal(t, `id: 1
+data: {"message":"first 1"}
+
+id: 2
+data: {"message":"second 1"}
+
+`, resp.Body.String())
+
+	// Reconnecting replays missed messages before new live ones.
+	resp = api.Get("/resume", "Last-Event-ID: 1")
+	assert.Equal(t, `id: 2
+data: {"message":"second 1"}
+
+id: 3
+data: {"message":"first 2"}
+
+id: 4
+data: {"message":"second 2"}
+
+`, resp.Body.String())
+
+	// Invalid IDs are ignored.
+	resp = api.Get("/resume", "Last-Event-ID: bad")
+	assert.Equal(t, `id: 5
+data: {"message":"first 3"}
+
+id: 6
+data: {"message":"second 3"}
+
+`, resp.Body.String())
+}
+
+type LogLine string
+
+type Point struct {
+	X int
+	Y int
+}
+
+func TestSSEEncoding(t *testing.T) {
+	_, api := humatest.New(t, huma.Config{
+		OpenAPI: &huma.OpenAPI{Info: &huma.Info{Title: "Test API", Version: "1.0.0"}},
+		Formats: map[string]huma.Format{
+			"application/json": huma.DefaultJSONFormat,
+			"text/csv": {
+				Marshal: func(w io.Writer, v any) error {
+					p := v.(Point)
+					_, err := fmt.Fprintf(w, "x,y\n%d,%d\n", p.X, p.Y)
+					return err
+				},
+			},
+		},
+	})
+
+	errs := []error{}
+	Register(api, huma.Operation{
+		OperationID: "sse-encoding",
+		Method:      http.MethodGet,
+		Path:        "/encoding",
+	}, map[string]any{
+		"message": DefaultMessage{},
+		"log":     LogLine(""),
+		"point":   Point{},
+	}, func(ctx context.Context, input *struct{}, send Sender) {
+		send.Data(LogLine("one\ntwo\r\nthree"))
+		send.Data(Point{X: 1, Y: 2})
+		send.Data(DefaultMessage{Message: "multi\nline"})
+		send.Data(123)
+	}, Options{
+		ContentTypes: map[string]string{
+			"log":   "text/plain; charset=utf-8",
+			"point": "text/csv",
+		},
+		OnError: func(ctx context.Context, err error) {
+			errs = append(errs, err)
+		},
+	})
+
+	resp := api.Get("/encoding")
+	assert.Equal(t, `event: log
+data: one
+data: two
+data: three
+
+event: point
+data: x,y
+data: 1,2
+
+data: {"message":"multi\nline"}
+
+data: 123
+
+`, resp.Body.String())
+
+	assert.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], ErrUnknownEvent)
+
+	// Multi-line data round-trips through the client.
+	events, err := ReadAll(strings.NewReader(resp.Body.String()), map[string]any{})
+	assert.NoError(t, err)
+	assert.Equal(t, json.RawMessage("one\ntwo\nthree"), events[0].Data)
+
+	// Text events are decoded using their content type.
+	events, err = ReadAll(strings.NewReader(resp.Body.String()), map[string]any{
+		"log": LogLine(""),
+	}, Options{ContentTypes: map[string]string{"log": "text/plain; charset=utf-8"}})
+	assert.NoError(t, err)
+	assert.Equal(t, LogLine("one\ntwo\nthree"), events[0].Data)
+
+	// Formats without an unmarshaler cannot be decoded.
+	_, err = ReadAll(strings.NewReader(resp.Body.String()), map[string]any{
+		"point": Point{},
+	}, Options{ContentTypes: map[string]string{"point": "text/csv"}})
+	assert.ErrorIs(t, err, ErrDecode)
+
+	// The documented event data matches its content type.
+	oneOf := api.OpenAPI().Paths["/encoding"].Get.Responses["200"].Content["text/event-stream"].Schema.Items.Extensions["oneOf"].([]*huma.Schema)
+	for _, event := range oneOf {
+		data := event.Properties["data"]
+		switch event.Title {
+		case "Event log":
+			assert.Equal(t, huma.TypeString, data.Type)
+			assert.Equal(t, "text/plain; charset=utf-8", data.Extensions["contentMediaType"])
+		case "Event message":
+			assert.Equal(t, "#/components/schemas/DefaultMessage", data.Ref)
+		}
+	}
+
+	// Binary formats cannot be sent as event data.
+	assert.PanicsWithValue(t, "sse: content type application/cbor for event point is not a text format", func() {
+		Register(api, huma.Operation{
+			Method: http.MethodGet,
+			Path:   "/binary",
+		}, map[string]any{"point": Point{}}, func(ctx context.Context, input *struct{}, send Sender) {}, Options{
+			ContentTypes: map[string]string{"point": "application/cbor"},
+		})
+	})
+}
+
+func TestDecodeText(t *testing.T) {
+	type Count int
+	type Raw []byte
+
+	for _, tc := range []struct {
+		data  string
+		value any
+	}{
+		{"hello world", LogLine("hello world")},
+		{"bytes", Raw("bytes")},
+		{"42", Count(42)},
+		{"true", true},
+	} {
+		decoded, err := decode(map[string]any{"text": tc.value}, map[string]string{"text": "text/plain"}, "text", []byte(tc.data))
+		assert.NoError(t, err)
+		assert.Equal(t, tc.value, decoded)
+	}
+}
+
+func TestSSEEncodeError(t *testing.T) {
+	_, api := humatest.New(t)
+
+	errs := []error{}
+	Register(api, huma.Operation{
+		OperationID: "sse-encode-error",
+		Method:      http.MethodGet,
+		Path:        "/encode-error",
+	}, map[string]any{
+		"message": make(chan int),
+	}, func(ctx context.Context, input *struct{}, send Sender) {
+		assert.Error(t, send.Data(make(chan int)))
+	}, Options{
+		OnError: func(ctx context.Context, err error) {
+			errs = append(errs, err)
+		},
+	})
+
+	resp := api.Get("/encode-error")
+	assert.Equal(t, "data: {\"error\": \"encode error: json: unsupported type: chan int\"}\n\n", resp.Body.String())
+	assert.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], "encode error: json: unsupported type: chan int")
+}
+
+func TestSSELogger(t *testing.T) {
+	logs := &bytes.Buffer{}
+	config := huma.DefaultConfig("Test API", "1.0.0")
+	config.Logger = slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	_, api := humatest.New(t, config)
+
+	Register(api, huma.Operation{
+		OperationID: "sse-log",
+		Method:      http.MethodGet,
+		Path:        "/log",
+	}, map[string]any{
+		"message": DefaultMessage{},
+	}, func(ctx context.Context, input *struct{}, send Sender) {
+		// Without an `OnError` hook, errors are logged.
+		send.Data(UserEvent{UserID: 1})
+	})
+
+	resp := api.Get("/log")
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, logs.String(), `level=ERROR msg="sse error" operationId=sse-log method=GET path=/log error="sse: unknown event type: sse.UserEvent"`)
+}
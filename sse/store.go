@@ -0,0 +1,104 @@
+package sse
+
+import "sync"
+
+// Store records sent messages so that clients reconnecting with a
+// `Last-Event-ID` header can be sent any messages they missed. Messages are
+// grouped by stream, see `Options.StreamID`. Implementations must be safe for
+// concurrent use.
+type Store interface {
+	// Append records a message for the stream and returns it. If the message
+	// has no ID then the next ID for the stream is assigned. IDs always
+	// increase within a stream.
+	Append(stream string, msg Message) (Message, error)
+
+	// Since returns the retained messages for the stream with an ID greater
+	// than `lastID`, in the order they were sent.
+	Since(stream string, lastID int) ([]Message, error)
+}
+
+// ring is a fixed-size circular buffer of messages for a single stream.
+type ring struct {
+	messages []Message
+	start    int
+	count    int
+	lastID   int
+}
+
+// MemoryStore is an in-memory `Store` which retains up to a fixed number of
+// the most recent messages per stream in a ring buffer. Clients which have
+// missed more messages than are retained will only receive the retained ones.
+type MemoryStore struct {
+	mu      sync.Mutex
+	size    int
+	streams map[string]*ring
+}
+
+// NewMemoryStore creates a new in-memory store which retains up to `size`
+// messages per stream.
+func NewMemoryStore(size int) *MemoryStore {
+	if size < 1 {
+		panic("sse: memory store size must be at least 1")
+	}
+	return &MemoryStore{
+		size:    size,
+		streams: map[string]*ring{},
+	}
+}
+
+// Append records a message for the stream. Messages without an ID, or with an
+// ID which is not greater than the previous one, are assigned the next ID.
+func (s *MemoryStore) Append(stream string, msg Message) (Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.streams[stream]
+	if r == nil {
+		r = &ring{messages: make([]Message, s.size)}
+		s.streams[stream] = r
+	}
+
+	if msg.ID <= r.lastID {
+		msg.ID = r.lastID + 1
+	}
+	r.lastID = msg.ID
+
+	if r.count < len(r.messages) {
+		r.messages[(r.start+r.count)%len(r.messages)] = msg
+		r.count++
+	} else {
+		// Full, so overwrite the oldest message.
+		r.messages[r.start] = msg
+		r.start = (r.start + 1) % len(r.messages)
+	}
+
+	return msg, nil
+}
+
+// Since returns the retained messages for the stream after `lastID`.
+func (s *MemoryStore) Since(stream string, lastID int) ([]Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.streams[stream]
+	if r == nil {
+		return nil, nil
+	}
+
+	var messages []Message
+	for i := 0; i < r.count; i++ {
+		msg := r.messages[(r.start+i)%len(r.messages)]
+		if msg.ID > lastID {
+			messages = append(messages, msg)
+		}
+	}
+	return messages, nil
+}
+
+// Delete removes all retained messages for the stream, e.g. once the stream
+// has ended and no clients are expected to resume it.
+func (s *MemoryStore) Delete(stream string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.streams, stream)
+}
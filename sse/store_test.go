@@ -0,0 +1,48 @@
+package sse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore(3)
+
+	for i := 0; i < 5; i++ {
+		msg, err := store.Append("a", Message{Data: i})
+		assert.NoError(t, err)
+		assert.Equal(t, i+1, msg.ID)
+	}
+
+	// Explicit IDs are kept if they increase, otherwise replaced.
+	msg, _ := store.Append("a", Message{ID: 10, Data: 5})
+	assert.Equal(t, 10, msg.ID)
+	msg, _ = store.Append("a", Message{ID: 2, Data: 6})
+	assert.Equal(t, 11, msg.ID)
+
+	// Only the last three messages are retained.
+	missed, err := store.Since("a", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []Message{{ID: 5, Data: 4}, {ID: 10, Data: 5}, {ID: 11, Data: 6}}, missed)
+
+	missed, _ = store.Since("a", 10)
+	assert.Equal(t, []Message{{ID: 11, Data: 6}}, missed)
+
+	missed, _ = store.Since("a", 11)
+	assert.Empty(t, missed)
+
+	// Streams are independent.
+	missed, _ = store.Since("b", 0)
+	assert.Empty(t, missed)
+	msg, _ = store.Append("b", Message{Data: "b"})
+	assert.Equal(t, 1, msg.ID)
+
+	store.Delete("a")
+	missed, _ = store.Since("a", 0)
+	assert.Empty(t, missed)
+
+	assert.Panics(t, func() {
+		NewMemoryStore(0)
+	})
+}
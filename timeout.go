@@ -0,0 +1,62 @@
+package huma
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+	"time"
+)
+
+// handlerPanic carries a panic from a handler running in another goroutine
+// along with the stack from where it occurred.
+type handlerPanic struct {
+	value any
+	stack []byte
+}
+
+// callWithTimeout calls the handler with a context which is canceled after
+// the timeout. If the handler has not returned by then, an error with the
+// given status is returned immediately and the handler's eventual result is
+// discarded. The returned channel is then non-nil and is closed once the
+// abandoned handler returns. Panics in the handler are re-raised in the
+// calling goroutine.
+func callWithTimeout[I, O any](ctx context.Context, timeout time.Duration, status int, handler func(context.Context, *I) (*O, error), input *I) (*O, <-chan struct{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type result struct {
+		output *O
+		err    error
+		panic  *handlerPanic
+	}
+	done := make(chan result, 1)
+	returned := make(chan struct{})
+	go func() {
+		var res result
+		defer func() {
+			if r := recover(); r != nil {
+				res.panic = &handlerPanic{value: r, stack: debug.Stack()}
+			}
+			done <- res
+			close(returned)
+		}()
+		res.output, res.err = handler(ctx, input)
+	}()
+
+	select {
+	case res := <-done:
+		if res.panic != nil {
+			panic(res.panic)
+		}
+		if res.err != nil && errors.Is(res.err, context.DeadlineExceeded) && ctx.Err() == context.DeadlineExceeded {
+			// The handler gave up because of the timeout.
+			return nil, nil, NewError(status, "operation timed out")
+		}
+		return res.output, nil, res.err
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, returned, NewError(status, "operation timed out")
+		}
+		return nil, returned, ctx.Err()
+	}
+}
@@ -0,0 +1,129 @@
+package huma
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeout(t *testing.T) {
+	config := DefaultConfig("Test API", "1.0.0")
+	config.Timeout = 20 * time.Millisecond
+	config.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	r := chi.NewRouter()
+	api := NewTestAdapter(r, config)
+
+	type SleepInput struct {
+		Sleep int `query:"sleep" doc:"Milliseconds to sleep"`
+	}
+	type SleepOutput struct {
+		Body string
+	}
+
+	sleep := func(ctx context.Context, input *SleepInput) (*SleepOutput, error) {
+		if input.Sleep < 0 {
+			// Honor the context like a well-behaved handler.
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		time.Sleep(time.Duration(input.Sleep) * time.Millisecond)
+		return &SleepOutput{Body: "done"}, nil
+	}
+
+	Register(api, Operation{
+		OperationID: "default",
+		Method:      http.MethodGet,
+		Path:        "/default",
+	}, sleep)
+
+	Register(api, Operation{
+		OperationID:   "gateway",
+		Method:        http.MethodGet,
+		Path:          "/gateway",
+		Timeout:       50 * time.Millisecond,
+		TimeoutStatus: http.StatusGatewayTimeout,
+	}, sleep)
+
+	Register(api, Operation{
+		OperationID: "unlimited",
+		Method:      http.MethodGet,
+		Path:        "/unlimited",
+		Timeout:     -1,
+	}, sleep)
+
+	Register(api, Operation{
+		OperationID: "stream",
+		Method:      http.MethodGet,
+		Path:        "/stream",
+	}, func(ctx context.Context, input *struct{}) (*StreamResponse, error) {
+		return &StreamResponse{
+			Body: func(ctx Context) {
+				time.Sleep(50 * time.Millisecond)
+				ctx.BodyWriter().Write([]byte("streamed"))
+			},
+		}, nil
+	})
+
+	Register(api, Operation{
+		OperationID: "panic",
+		Method:      http.MethodGet,
+		Path:        "/panic",
+	}, func(ctx context.Context, input *struct{}) (*struct{}, error) {
+		panic("boom")
+	})
+
+	do := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("/default?sleep=1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"done"`, w.Body.String()[:6])
+
+	// Handlers which ignore the context are abandoned.
+	start := time.Now()
+	w = do("/default?sleep=1000")
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), "operation timed out")
+
+	// Handlers which give up when the context is canceled.
+	w = do("/default?sleep=-1")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	w = do("/gateway?sleep=1000")
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+
+	w = do("/gateway?sleep=30")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = do("/unlimited?sleep=30")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Streaming responses are not subject to the timeout.
+	w = do("/stream")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "streamed", w.Body.String())
+
+	// Panics in the handler goroutine are still recovered.
+	w = do("/panic")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	// The timeout response is documented.
+	paths := api.OpenAPI().Paths
+	assert.NotNil(t, paths["/default"].Get.Responses["503"])
+	assert.NotNil(t, paths["/gateway"].Get.Responses["504"])
+	assert.Nil(t, paths["/gateway"].Get.Responses["503"])
+	assert.Nil(t, paths["/unlimited"].Get.Responses["503"])
+	assert.Nil(t, paths["/stream"].Get.Responses["503"])
+}
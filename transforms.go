@@ -146,6 +146,12 @@ func (t *SchemaLinkTransformer) Transform(ctx Context, status string, v any) (an
 		}
 	}
 
+	if e, ok := v.(extender); ok && len(e.extensionMembers()) > 0 {
+		// The wrapper type loses the original marshaling methods, so make sure
+		// any extension members still get sent to the client.
+		return &withExtensions{tmp.Addr().Interface(), e.extensionMembers()}, nil
+	}
+
 	return tmp.Addr().Interface(), nil
 }
 
@@ -154,11 +154,27 @@ func NewPathBuffer(buf []byte, offset int) *PathBuffer {
 // validations as long as `Reset()` is called between uses.
 type ValidateResult struct {
 	Errors []error
+
+	// Locale selects the message catalog used for error messages. If empty,
+	// or if no catalog is registered for the locale, the default English
+	// messages are used. See `huma.RegisterMessageCatalog`.
+	Locale string
 }
 
 // Add an error to the validation result at the given path and with the
-// given value.
+// given value. The message is translated if the result's locale has a
+// translation for it.
 func (r *ValidateResult) Add(path *PathBuffer, v any, msg string) {
+	r.Errors = append(r.Errors, &ErrorDetail{
+		Message:  Msg(r.Locale, msg),
+		Location: path.String(),
+		Value:    v,
+	})
+}
+
+// addTranslated adds an error with a message which has already been
+// translated for the result's locale, e.g. a precomputed schema message.
+func (r *ValidateResult) addTranslated(path *PathBuffer, v any, msg string) {
 	r.Errors = append(r.Errors, &ErrorDetail{
 		Message:  msg,
 		Location: path.String(),
@@ -170,7 +186,7 @@ func (r *ValidateResult) Add(path *PathBuffer, v any, msg string) {
 // the given value, allowing for fmt.Printf-style formatting.
 func (r *ValidateResult) Addf(path *PathBuffer, v any, format string, args ...any) {
 	r.Errors = append(r.Errors, &ErrorDetail{
-		Message:  fmt.Sprintf(format, args...),
+		Message:  fmt.Sprintf(Msg(r.Locale, format), args...),
 		Location: path.String(),
 		Value:    v,
 	})
@@ -179,6 +195,7 @@ func (r *ValidateResult) Addf(path *PathBuffer, v any, format string, args ...an
 // Reset the validation error so it can be used again.
 func (r *ValidateResult) Reset() {
 	r.Errors = r.Errors[:0]
+	r.Locale = ""
 }
 
 func validateFormat(path *PathBuffer, str string, s *Schema, res *ValidateResult) {
@@ -192,72 +209,72 @@ func validateFormat(path *PathBuffer, str string, s *Schema, res *ValidateResult
 			}
 		}
 		if !found {
-			res.Add(path, str, "expected string to be RFC 3339 date-time")
+			res.Add(path, str, MsgExpectedRFC3339DateTime)
 		}
 	case "date-time-http":
 		if _, err := time.Parse(time.RFC1123, str); err != nil {
-			res.Add(path, str, "expected string to be RFC 1123 date-time")
+			res.Add(path, str, MsgExpectedRFC1123DateTime)
 		}
 	case "date":
 		if _, err := time.Parse("2006-01-02", str); err != nil {
-			res.Add(path, str, "expected string to be RFC 3339 date")
+			res.Add(path, str, MsgExpectedRFC3339Date)
 		}
 	case "time":
 		if _, err := time.Parse("15:04:05", str); err != nil {
 			if _, err := time.Parse("15:04:05Z07:00", str); err != nil {
-				res.Add(path, str, "expected string to be RFC 3339 time")
+				res.Add(path, str, MsgExpectedRFC3339Time)
 			}
 		}
 		// TODO: duration
 	case "email", "idn-email":
 		if _, err := mail.ParseAddress(str); err != nil {
-			res.Addf(path, str, "expected string to be RFC 5322 email: %v", err)
+			res.Addf(path, str, MsgExpectedRFC5322Email, err)
 		}
 	case "hostname":
 		if !(rxHostname.MatchString(str) && len(str) < 256) {
-			res.Add(path, str, "expected string to be RFC 5890 hostname")
+			res.Add(path, str, MsgExpectedRFC5890Hostname)
 		}
 	case "idn-hostname":
 		if _, err := idna.ToASCII(str); err != nil {
-			res.Addf(path, str, "expected string to be RFC 5890 hostname: %v", err)
+			res.Addf(path, str, MsgExpectedRFC5890IDN, err)
 		}
 	case "ipv4":
 		if ip := net.ParseIP(str); ip == nil || ip.To4() == nil {
-			res.Add(path, str, "expected string to be RFC 2673 ipv4")
+			res.Add(path, str, MsgExpectedRFC2673IPv4)
 		}
 	case "ipv6":
 		if ip := net.ParseIP(str); ip == nil || ip.To16() == nil {
-			res.Add(path, str, "expected string to be RFC 2373 ipv6")
+			res.Add(path, str, MsgExpectedRFC2373IPv6)
 		}
 	case "uri", "uri-reference", "iri", "iri-reference":
 		if _, err := url.Parse(str); err != nil {
-			res.Addf(path, str, "expected string to be RFC 3986 uri: %v", err)
+			res.Addf(path, str, MsgExpectedRFC3986URI, err)
 		}
 		// TODO: check if it's actually a reference?
 	case "uuid":
 		if _, err := uuid.Parse(str); err != nil {
-			res.Addf(path, str, "expected string to be RFC 4122 uuid: %v", err)
+			res.Addf(path, str, MsgExpectedRFC4122UUID, err)
 		}
 	case "uri-template":
 		u, err := url.Parse(str)
 		if err != nil {
-			res.Addf(path, str, "expected string to be RFC 3986 uri: %v", err)
+			res.Addf(path, str, MsgExpectedRFC3986URI, err)
 			return
 		}
 		if !rxURITemplate.MatchString(u.Path) {
-			res.Add(path, str, "expected string to be RFC 6570 uri-template")
+			res.Add(path, str, MsgExpectedRFC6570Template)
 		}
 	case "json-pointer":
 		if !rxJSONPointer.MatchString(str) {
-			res.Add(path, str, "expected string to be RFC 6901 json-pointer")
+			res.Add(path, str, MsgExpectedRFC6901Pointer)
 		}
 	case "relative-json-pointer":
 		if !rxRelJSONPointer.MatchString(str) {
-			res.Add(path, str, "expected string to be RFC 6901 relative-json-pointer")
+			res.Add(path, str, MsgExpectedRFC6901RelPtr)
 		}
 	case "regex":
 		if _, err := regexp.Compile(str); err != nil {
-			res.Addf(path, str, "expected string to be regex: %v", err)
+			res.Addf(path, str, MsgExpectedRegex, err)
 		}
 	}
 }
@@ -287,7 +304,7 @@ func Validate(r Registry, s *Schema, path *PathBuffer, mode ValidateMode, v any,
 	switch s.Type {
 	case TypeBoolean:
 		if _, ok := v.(bool); !ok {
-			res.Add(path, v, "expected boolean")
+			res.Add(path, v, MsgExpectedBoolean)
 			return
 		}
 	case TypeNumber, TypeInteger:
@@ -319,33 +336,33 @@ func Validate(r Registry, s *Schema, path *PathBuffer, mode ValidateMode, v any,
 		case uint64:
 			num = float64(v)
 		default:
-			res.Add(path, v, "expected number")
+			res.Add(path, v, MsgExpectedNumber)
 			return
 		}
 
 		if s.Minimum != nil {
 			if num < *s.Minimum {
-				res.Addf(path, v, s.msgMinimum)
+				res.addTranslated(path, v, s.messages(res.Locale).minimum)
 			}
 		}
 		if s.ExclusiveMinimum != nil {
 			if num <= *s.ExclusiveMinimum {
-				res.Addf(path, v, s.msgExclusiveMinimum)
+				res.addTranslated(path, v, s.messages(res.Locale).exclusiveMinimum)
 			}
 		}
 		if s.Maximum != nil {
 			if num > *s.Maximum {
-				res.Add(path, v, s.msgMaximum)
+				res.addTranslated(path, v, s.messages(res.Locale).maximum)
 			}
 		}
 		if s.ExclusiveMaximum != nil {
 			if num >= *s.ExclusiveMaximum {
-				res.Addf(path, v, s.msgExclusiveMaximum)
+				res.addTranslated(path, v, s.messages(res.Locale).exclusiveMaximum)
 			}
 		}
 		if s.MultipleOf != nil {
 			if math.Mod(num, *s.MultipleOf) != 0 {
-				res.Addf(path, v, s.msgMultipleOf)
+				res.addTranslated(path, v, s.messages(res.Locale).multipleOf)
 			}
 		}
 	case TypeString:
@@ -354,24 +371,24 @@ func Validate(r Registry, s *Schema, path *PathBuffer, mode ValidateMode, v any,
 			if b, ok := v.([]byte); ok {
 				str = *(*string)(unsafe.Pointer(&b))
 			} else {
-				res.Add(path, v, "expected string")
+				res.Add(path, v, MsgExpectedString)
 				return
 			}
 		}
 
 		if s.MinLength != nil {
 			if len(str) < *s.MinLength {
-				res.Addf(path, str, s.msgMinLength)
+				res.addTranslated(path, str, s.messages(res.Locale).minLength)
 			}
 		}
 		if s.MaxLength != nil {
 			if len(str) > *s.MaxLength {
-				res.Add(path, str, s.msgMaxLength)
+				res.addTranslated(path, str, s.messages(res.Locale).maxLength)
 			}
 		}
 		if s.patternRe != nil {
 			if !s.patternRe.MatchString(str) {
-				res.Add(path, v, s.msgPattern)
+				res.addTranslated(path, v, s.messages(res.Locale).pattern)
 			}
 		}
 
@@ -381,7 +398,7 @@ func Validate(r Registry, s *Schema, path *PathBuffer, mode ValidateMode, v any,
 
 		if s.ContentEncoding == "base64" {
 			if !rxBase64.MatchString(str) {
-				res.Add(path, str, "expected string to be base64 encoded")
+				res.Add(path, str, MsgExpectedBase64)
 			}
 		}
 	case TypeArray:
@@ -392,7 +409,7 @@ func Validate(r Registry, s *Schema, path *PathBuffer, mode ValidateMode, v any,
 			// Special case for params which are lists.
 			handleArray(r, s, path, mode, res, arr)
 		default:
-			res.Add(path, v, "expected array")
+			res.Add(path, v, MsgExpectedArray)
 			return
 		}
 	case TypeObject:
@@ -400,7 +417,7 @@ func Validate(r Registry, s *Schema, path *PathBuffer, mode ValidateMode, v any,
 			handleMapString(r, s, path, mode, vv, res)
 			// TODO: handle map[any]any
 		} else {
-			res.Add(path, v, "expected object")
+			res.Add(path, v, MsgExpectedObject)
 			return
 		}
 	}
@@ -414,7 +431,7 @@ func Validate(r Registry, s *Schema, path *PathBuffer, mode ValidateMode, v any,
 			}
 		}
 		if !found {
-			res.Add(path, v, s.msgEnum)
+			res.addTranslated(path, v, s.messages(res.Locale).enum)
 		}
 	}
 }
@@ -422,12 +439,12 @@ func Validate(r Registry, s *Schema, path *PathBuffer, mode ValidateMode, v any,
 func handleArray[T any](r Registry, s *Schema, path *PathBuffer, mode ValidateMode, res *ValidateResult, arr []T) {
 	if s.MinItems != nil {
 		if len(arr) < *s.MinItems {
-			res.Addf(path, arr, s.msgMinItems)
+			res.addTranslated(path, arr, s.messages(res.Locale).minItems)
 		}
 	}
 	if s.MaxItems != nil {
 		if len(arr) > *s.MaxItems {
-			res.Addf(path, arr, s.msgMaxItems)
+			res.addTranslated(path, arr, s.messages(res.Locale).maxItems)
 		}
 	}
 
@@ -435,7 +452,7 @@ func handleArray[T any](r Registry, s *Schema, path *PathBuffer, mode ValidateMo
 		seen := make(map[any]struct{}, len(arr))
 		for _, item := range arr {
 			if _, ok := seen[item]; ok {
-				res.Add(path, arr, "expected array items to be unique")
+				res.Add(path, arr, MsgExpectedUniqueItems)
 			}
 			seen[item] = struct{}{}
 		}
@@ -451,12 +468,12 @@ func handleArray[T any](r Registry, s *Schema, path *PathBuffer, mode ValidateMo
 func handleMapString(r Registry, s *Schema, path *PathBuffer, mode ValidateMode, m map[string]any, res *ValidateResult) {
 	if s.MinProperties != nil {
 		if len(m) < *s.MinProperties {
-			res.Add(path, m, s.msgMinProperties)
+			res.addTranslated(path, m, s.messages(res.Locale).minProperties)
 		}
 	}
 	if s.MaxProperties != nil {
 		if len(m) > *s.MaxProperties {
-			res.Add(path, m, s.msgMaxProperties)
+			res.addTranslated(path, m, s.messages(res.Locale).maxProperties)
 		}
 	}
 
@@ -472,7 +489,7 @@ func handleMapString(r Registry, s *Schema, path *PathBuffer, mode ValidateMode,
 
 		// Be stricter for responses, enabling validation of the server if desired.
 		if mode == ModeReadFromServer && v.WriteOnly && m[k] != nil && !reflect.ValueOf(m[k]).IsZero() {
-			res.Add(path, m[k], "write only property is non-zero")
+			res.Add(path, m[k], MsgWriteOnlyNonZero)
 			continue
 		}
 
@@ -485,7 +502,7 @@ func handleMapString(r Registry, s *Schema, path *PathBuffer, mode ValidateMode,
 				// These are not required for the current mode.
 				continue
 			}
-			res.Add(path, m, s.msgRequired[k])
+			res.addTranslated(path, m, s.messages(res.Locale).required[k])
 			continue
 		}
 
@@ -499,7 +516,7 @@ func handleMapString(r Registry, s *Schema, path *PathBuffer, mode ValidateMode,
 			// No additional properties allowed.
 			if _, ok := s.Properties[k]; !ok {
 				path.Push(k)
-				res.Add(path, m, "unexpected property")
+				res.Add(path, m, MsgUnexpectedProperty)
 				path.Pop()
 			}
 		}
@@ -0,0 +1,333 @@
+// Package websocket provides utilities for registering WebSocket operations
+// which send and receive typed, validated messages.
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/danielgtaylor/huma/v2"
+	"golang.org/x/net/websocket"
+)
+
+// ErrClosed is returned when using a connection which has been closed.
+var ErrClosed = errors.New("websocket: connection closed")
+
+// MetadataKey is the operation metadata key under which `Register` stores a
+// `map[string]*huma.Schema` with the `clientMessage` and `serverMessage`
+// schemas, e.g. for generating AsyncAPI documents.
+const MetadataKey = "websocket"
+
+// Options configures optional WebSocket behavior when registering an
+// operation.
+type Options struct {
+	// CheckOrigin returns whether to accept the connection based on the
+	// request's `Origin` header. Defaults to allowing requests with no origin
+	// or an origin matching the request host to prevent cross-site WebSocket
+	// hijacking.
+	CheckOrigin func(ctx huma.Context) bool
+}
+
+// sameOrigin returns true if the request has no `Origin` header or if its
+// host matches the request host.
+func sameOrigin(ctx huma.Context) bool {
+	origin := ctx.Header("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, ctx.Host())
+}
+
+// isText returns true if messages in the content type should be sent as text
+// frames rather than binary frames.
+func isText(contentType string) bool {
+	return strings.HasPrefix(contentType, "text/") || strings.Contains(contentType, "json") || strings.Contains(contentType, "yaml")
+}
+
+// Conn is a WebSocket connection which receives messages of type `In` from
+// the client and sends messages of type `Out` to the client. Messages use
+// the format negotiated via the request's `Accept` header, which defaults to
+// the API's default format. It is safe to send and receive concurrently.
+type Conn[In, Out any] struct {
+	api         huma.API
+	ctx         huma.Context
+	ws          *websocket.Conn
+	contentType string
+	text        bool
+	schema      *huma.Schema
+
+	// closed is canceled when the connection fails or is closed.
+	closed context.Context
+	cancel context.CancelFunc
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+	pb  *huma.PathBuffer
+	res *huma.ValidateResult
+}
+
+// ContentType returns the content type used to encode messages.
+func (c *Conn[In, Out]) ContentType() string {
+	return c.contentType
+}
+
+// fail closes the connection and returns `ErrClosed` if the error means the
+// connection is no longer usable.
+func (c *Conn[In, Out]) fail(err error) error {
+	if c.closed.Err() != nil {
+		return ErrClosed
+	}
+	c.cancel()
+	return err
+}
+
+// Receive reads the next message from the client and validates it against
+// the schema for `In`. Invalid messages return a `huma.StatusError` with
+// status `400` or `422` and leave the connection open, so the handler may
+// ignore them or close the connection. Other errors such as `io.EOF` mean the
+// client has gone away.
+func (c *Conn[In, Out]) Receive() (In, error) {
+	var msg In
+	var data []byte
+	if err := websocket.Message.Receive(c.ws, &data); err != nil {
+		if errors.Is(err, websocket.ErrFrameTooLarge) {
+			return msg, huma.NewError(http.StatusRequestEntityTooLarge, "Message is too large")
+		}
+		return msg, c.fail(err)
+	}
+
+	var parsed any
+	if err := c.api.Unmarshal(c.contentType, data, &parsed); err != nil {
+		return msg, huma.NewError(http.StatusBadRequest, "Unable to parse message", &huma.ErrorDetail{
+			Location: "body",
+			Message:  err.Error(),
+			Value:    string(data),
+		})
+	}
+
+	c.mu.Lock()
+	c.pb.Reset()
+	c.pb.Push("body")
+	c.res.Reset()
+	huma.Validate(c.api.OpenAPI().Components.Schemas, c.schema, c.pb, huma.ModeWriteToServer, parsed, c.res)
+	errs := append([]error{}, c.res.Errors...)
+	c.mu.Unlock()
+	if len(errs) > 0 {
+		return msg, huma.NewError(http.StatusUnprocessableEntity, "Validation failed", errs...)
+	}
+
+	if err := c.api.Unmarshal(c.contentType, data, &msg); err != nil {
+		return msg, huma.NewError(http.StatusBadRequest, "Unable to parse message", err)
+	}
+	return msg, nil
+}
+
+// Send a message to the client.
+func (c *Conn[In, Out]) Send(msg Out) error {
+	if c.closed.Err() != nil {
+		return ErrClosed
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.buf.Reset()
+	if err := c.api.Marshal(huma.WithBodyWriter(c.ctx, &c.buf), "", c.contentType, msg); err != nil {
+		return err
+	}
+
+	var err error
+	if c.text {
+		err = websocket.Message.Send(c.ws, c.buf.String())
+	} else {
+		err = websocket.Message.Send(c.ws, c.buf.Bytes())
+	}
+	if err != nil {
+		return c.fail(err)
+	}
+	return nil
+}
+
+// Close the connection.
+func (c *Conn[In, Out]) Close() error {
+	c.cancel()
+	return c.ws.Close()
+}
+
+// Register a new WebSocket operation. The input `I` is parsed & validated
+// from the upgrade request like any other operation, then `f` is called with
+// a connection for exchanging `In` messages from the client and `Out`
+// messages to the client. The context passed to `f` is canceled when the
+// connection fails or is closed. The connection is closed when `f` returns.
+//
+// The adapter's `BodyWriter` must support `http.Hijacker`, which is the case
+// for adapters built on `net/http` serving HTTP/1.x. Otherwise a `501 Not
+// Implemented` error is returned. The message schemas are documented via the
+// `x-websocket` operation extension.
+func Register[I, In, Out any](api huma.API, op huma.Operation, f func(ctx context.Context, input *I, conn *Conn[In, Out]), options ...Options) {
+	var opts Options
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.CheckOrigin == nil {
+		opts.CheckOrigin = sameOrigin
+	}
+
+	if op.Method == "" {
+		op.Method = http.MethodGet
+	}
+	if op.DefaultStatus == 0 {
+		op.DefaultStatus = http.StatusSwitchingProtocols
+	}
+	if op.MaxBodyBytes == 0 {
+		// 1 MB default per message.
+		op.MaxBodyBytes = 1024 * 1024
+	}
+	for _, status := range []int{http.StatusForbidden, http.StatusUpgradeRequired, http.StatusNotImplemented} {
+		found := false
+		for _, e := range op.Errors {
+			if e == status {
+				found = true
+				break
+			}
+		}
+		if !found {
+			op.Errors = append(op.Errors, status)
+		}
+	}
+
+	registry := api.OpenAPI().Components.Schemas
+	name := op.OperationID
+	if name == "" {
+		name = "WebSocket"
+	}
+	inSchema := registry.Schema(reflect.TypeOf((*In)(nil)).Elem(), true, name+"ClientMessage")
+	outSchema := registry.Schema(reflect.TypeOf((*Out)(nil)).Elem(), true, name+"ServerMessage")
+	if op.Extensions == nil {
+		op.Extensions = map[string]any{}
+	}
+	op.Extensions["x-websocket"] = map[string]any{
+		"clientMessage": inSchema,
+		"serverMessage": outSchema,
+	}
+	metadata := make(map[string]any, len(op.Metadata)+1)
+	for k, v := range op.Metadata {
+		metadata[k] = v
+	}
+	metadata[MetadataKey] = map[string]*huma.Schema{
+		"clientMessage": inSchema,
+		"serverMessage": outSchema,
+	}
+	op.Metadata = metadata
+
+	huma.Register(api, op, func(ctx context.Context, input *I) (*huma.StreamResponse, error) {
+		return &huma.StreamResponse{
+			Body: func(ctx huma.Context) {
+				if !strings.EqualFold(ctx.Header("Upgrade"), "websocket") {
+					ctx.SetHeader("Upgrade", "websocket")
+					huma.WriteErr(api, ctx, http.StatusUpgradeRequired, "WebSocket upgrade required")
+					return
+				}
+
+				if !opts.CheckOrigin(ctx) {
+					huma.WriteErr(api, ctx, http.StatusForbidden, "Origin not allowed")
+					return
+				}
+
+				ct, err := api.Negotiate(ctx.Header("Accept"))
+				if err != nil {
+					huma.WriteErr(api, ctx, http.StatusNotAcceptable, "unable to marshal response", err)
+					return
+				}
+
+				// Hijack up front since wrapped writers always have a `Hijack`
+				// method, which fails if the underlying writer cannot hijack,
+				// e.g. for HTTP/2 requests.
+				h, ok := ctx.BodyWriter().(http.Hijacker)
+				if !ok {
+					huma.WriteErr(api, ctx, http.StatusNotImplemented, "WebSocket not supported")
+					return
+				}
+				netConn, rw, err := h.Hijack()
+				if err != nil {
+					huma.WriteErr(api, ctx, http.StatusNotImplemented, "WebSocket not supported", err)
+					return
+				}
+
+				server := websocket.Server{
+					// The origin has already been checked above.
+					Handshake: func(*websocket.Config, *http.Request) error { return nil },
+					Handler: func(ws *websocket.Conn) {
+						ws.MaxPayloadBytes = int(op.MaxBodyBytes)
+						conn := &Conn[In, Out]{
+							api:         api,
+							ctx:         ctx,
+							ws:          ws,
+							contentType: ct,
+							text:        isText(ct),
+							schema:      inSchema,
+							pb:          huma.NewPathBuffer([]byte{}, 0),
+							res:         &huma.ValidateResult{},
+						}
+						conn.closed, conn.cancel = context.WithCancel(ctx.Context())
+						defer conn.cancel()
+						f(conn.closed, input, conn)
+					},
+				}
+				server.ServeHTTP(&hijackWriter{conn: netConn, rw: rw}, upgradeRequest(ctx))
+			},
+		}, nil
+	})
+}
+
+// hijackWriter adapts an already hijacked connection into the response
+// writer expected by the WebSocket server. Only `Hijack` is used.
+type hijackWriter struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+}
+
+func (w *hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.conn, w.rw, nil
+}
+
+func (w *hijackWriter) Header() http.Header {
+	return http.Header{}
+}
+
+func (w *hijackWriter) Write(b []byte) (int, error) {
+	return 0, http.ErrHijacked
+}
+
+func (w *hijackWriter) WriteHeader(int) {}
+
+// upgradeRequest builds the request used for the WebSocket handshake from
+// the router-agnostic context.
+func upgradeRequest(ctx huma.Context) *http.Request {
+	u := ctx.URL()
+	r := &http.Request{
+		Method:     ctx.Method(),
+		URL:        &u,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Host:       ctx.Host(),
+		RequestURI: u.RequestURI(),
+	}
+	ctx.EachHeader(func(name, value string) {
+		r.Header.Add(name, value)
+	})
+	return r.WithContext(ctx.Context())
+}
@@ -0,0 +1,138 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/danielgtaylor/huma/v2"
+	"github.com/danielgtaylor/huma/v2/compress"
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+type ChatMessage struct {
+	Text string `json:"text" maxLength:"5"`
+}
+
+type ChatReply struct {
+	Room   string `json:"room"`
+	Echo   string `json:"echo,omitempty"`
+	Status int    `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+func newChat(t *testing.T) (humatest.TestAPI, *httptest.Server) {
+	_, api := humatest.New(t)
+
+	// Compression must not interfere with upgrading the connection.
+	api.UseMiddleware(compress.New(api, compress.Options{}))
+
+	Register(api, huma.Operation{
+		OperationID: "chat",
+		Path:        "/chat/{room}",
+	}, func(ctx context.Context, input *struct {
+		Room string `path:"room" maxLength:"10"`
+	}, conn *Conn[ChatMessage, ChatReply]) {
+		for {
+			msg, err := conn.Receive()
+			if err != nil {
+				var se huma.StatusError
+				if errors.As(err, &se) {
+					conn.Send(ChatReply{Room: input.Room, Status: se.GetStatus(), Error: se.Error()})
+					continue
+				}
+				return
+			}
+			if msg.Text == "bye" {
+				return
+			}
+			conn.Send(ChatReply{Room: input.Room, Echo: msg.Text})
+		}
+	})
+
+	server := httptest.NewServer(api.Adapter())
+	t.Cleanup(server.Close)
+	return api, server
+}
+
+func dial(t *testing.T, server *httptest.Server, path, origin string) (*websocket.Conn, error) {
+	config, err := websocket.NewConfig("ws"+strings.TrimPrefix(server.URL, "http")+path, origin)
+	require.NoError(t, err)
+	config.Header.Set("Accept-Encoding", "gzip")
+	return websocket.DialConfig(config)
+}
+
+func TestWebSocket(t *testing.T) {
+	_, server := newChat(t)
+
+	ws, err := dial(t, server, "/chat/general", server.URL)
+	require.NoError(t, err)
+	defer ws.Close()
+
+	var reply ChatReply
+	require.NoError(t, websocket.JSON.Send(ws, ChatMessage{Text: "hi"}))
+	require.NoError(t, websocket.JSON.Receive(ws, &reply))
+	assert.Equal(t, ChatReply{Room: "general", Echo: "hi"}, reply)
+
+	// Invalid messages are reported without closing the connection.
+	require.NoError(t, websocket.JSON.Send(ws, ChatMessage{Text: "too long"}))
+	reply = ChatReply{}
+	require.NoError(t, websocket.JSON.Receive(ws, &reply))
+	assert.Equal(t, ChatReply{Room: "general", Status: http.StatusUnprocessableEntity, Error: "Validation failed"}, reply)
+
+	require.NoError(t, websocket.Message.Send(ws, "{bad"))
+	reply = ChatReply{}
+	require.NoError(t, websocket.JSON.Receive(ws, &reply))
+	assert.Equal(t, ChatReply{Room: "general", Status: http.StatusBadRequest, Error: "Unable to parse message"}, reply)
+
+	require.NoError(t, websocket.JSON.Send(ws, ChatMessage{Text: "hello"}))
+	reply = ChatReply{}
+	require.NoError(t, websocket.JSON.Receive(ws, &reply))
+	assert.Equal(t, ChatReply{Room: "general", Echo: "hello"}, reply)
+
+	// The server closes the connection when the handler returns.
+	require.NoError(t, websocket.JSON.Send(ws, ChatMessage{Text: "bye"}))
+	assert.Error(t, websocket.JSON.Receive(ws, &reply))
+}
+
+func TestWebSocketErrors(t *testing.T) {
+	api, server := newChat(t)
+
+	// Cross-origin connections are rejected.
+	_, err := dial(t, server, "/chat/general", "https://evil.example.com")
+	assert.Error(t, err)
+
+	// Input validation still applies to the upgrade request.
+	_, err = dial(t, server, "/chat/this-room-name-is-too-long", server.URL)
+	assert.Error(t, err)
+
+	// Regular requests must upgrade.
+	resp := api.Get("/chat/general")
+	assert.Equal(t, http.StatusUpgradeRequired, resp.Code)
+	assert.Equal(t, "websocket", resp.Header().Get("Upgrade"))
+
+	// Writers which cannot hijack the connection are rejected, even when
+	// wrapped by middleware like compression.
+	resp = api.Get("/chat/general", "Upgrade: websocket", "Connection: Upgrade", "Accept-Encoding: gzip")
+	assert.Equal(t, http.StatusNotImplemented, resp.Code, resp.Body.String())
+}
+
+func TestWebSocketDocs(t *testing.T) {
+	api, _ := newChat(t)
+
+	op := api.OpenAPI().Paths["/chat/{room}"].Get
+	assert.Equal(t, http.StatusSwitchingProtocols, op.DefaultStatus)
+	assert.NotNil(t, op.Responses["101"])
+	assert.NotNil(t, op.Responses["426"])
+	assert.NotNil(t, op.Responses["501"])
+
+	ext := op.Extensions["x-websocket"].(map[string]any)
+	assert.Equal(t, "#/components/schemas/ChatMessage", ext["clientMessage"].(*huma.Schema).Ref)
+	assert.Equal(t, "#/components/schemas/ChatReply", ext["serverMessage"].(*huma.Schema).Ref)
+}